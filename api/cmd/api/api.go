@@ -2,10 +2,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/stevecshanks/next-actions-in-go/api/internal/config"
@@ -13,11 +18,74 @@ import (
 	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
 )
 
+// server holds state shared between requests, so that configuration is only loaded once and Trello responses can be
+// cached and connections reused
+type server struct {
+	cfg          *config.Config
+	client       *trello.Client
+	lists        *nextactions.ListResolver
+	projectCards *nextactions.ProjectCardCache
+}
+
+func newServer(cfg *config.Config) *server {
+	options := []trello.ClientOption{trello.WithCache(trello.NewMemoryCache(), nil)}
+	if cfg.TrelloAPIBaseURL != "" {
+		options = append(options, trello.WithBaseURL(cfg.TrelloAPIBaseURL))
+	}
+
+	client := trello.NewClient(cfg.TrelloKey, cfg.TrelloToken, options...)
+
+	return &server{
+		cfg:          cfg,
+		client:       client,
+		lists:        &nextactions.ListResolver{Client: client, Config: cfg},
+		projectCards: &nextactions.ProjectCardCache{TTL: cfg.TrelloListRefreshInterval},
+	}
+}
+
+// config returns the configuration with any lists that are configured by name replaced by their IDs
+func (s *server) config() *config.Config {
+	return s.lists.Current()
+}
+
+// resolveLists looks up the IDs of any lists that are configured by name
+func (s *server) resolveLists() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.RequestTimeout)
+	defer cancel()
+
+	return s.lists.Resolve(ctx)
+}
+
 type apiError struct {
 	Detail string `json:"detail"`
 }
 
+// dataResponse holds the actions, or other items such as those on the Waiting For list, returned by an endpoint
+type dataResponse struct {
+	Data interface{} `json:"data"`
+	Meta actionsMeta `json:"meta"`
+}
+
+type actionsMeta struct {
+	Warnings []nextactions.Warning `json:"warnings"`
+	// InboxCount is only set if an Inbox list is configured, so that the user can be prompted to process it
+	InboxCount *int `json:"inboxCount,omitempty"`
+}
+
 func handleError(w http.ResponseWriter, err error) {
+	var listNotConfiguredError *nextactions.ListNotConfiguredError
+	if errors.As(err, &listNotConfiguredError) {
+		handleErrorWithStatus(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		handleErrorWithStatus(w, http.StatusGatewayTimeout, err)
+		return
+	}
+	handleErrorWithStatus(w, http.StatusInternalServerError, err)
+}
+
+func handleErrorWithStatus(w http.ResponseWriter, statusCode int, err error) {
 	fmt.Printf("Error: %s\n", err)
 
 	apiErrors := []apiError{{err.Error()}}
@@ -28,42 +96,266 @@ func handleError(w http.ResponseWriter, err error) {
 	}
 	body = append(body, "\n"...)
 
-	w.WriteHeader(http.StatusInternalServerError)
+	w.WriteHeader(statusCode)
 	_, err = w.Write(body)
 	if err != nil {
 		panic(err)
 	}
 }
 
-func actions(w http.ResponseWriter, req *http.Request) {
-	cfg, err := config.FromEnvironment()
+func (s *server) actions(w http.ResponseWriter, req *http.Request) {
+	sources := []string{
+		nextactions.ActionSourceOwned,
+		nextactions.ActionSourceNextActionsList,
+		nextactions.ActionSourceProjectTodo,
+	}
+	s.serveActions(w, req, fetchActions, sources)
+}
+
+func fetchActions(
+	ctx context.Context,
+	fetcher *nextactions.Fetcher,
+	query *actionsQuery,
+) (interface{}, actionsMeta, error) {
+	inboxCounts := countInbox(ctx, fetcher)
+
+	actions, warnings, err := fetcher.Fetch(ctx)
 	if err != nil {
-		handleError(w, err)
+		return nil, actionsMeta{}, err
+	}
+
+	meta := actionsMeta{Warnings: warnings}
+	if inboxCounts != nil {
+		addInboxCount(&meta, <-inboxCounts)
+	}
+	return nextactions.FilterActions(actions, query.filter), meta, nil
+}
+
+type inboxCountResult struct {
+	count int
+	err   error
+}
+
+// countInbox starts counting the cards on the Inbox list while the actions are fetched, returning nil if there is no
+// Inbox list
+func countInbox(ctx context.Context, fetcher *nextactions.Fetcher) <-chan inboxCountResult {
+	if fetcher.Config.TrelloInboxListID == "" {
+		return nil
+	}
+	results := make(chan inboxCountResult, 1)
+	go func() {
+		count, err := fetcher.CountInbox(ctx)
+		results <- inboxCountResult{count, err}
+	}()
+	return results
+}
+
+// addInboxCount adds the count to the meta, or a warning if the Inbox could not be read, as the actions are still
+// useful without it
+func addInboxCount(meta *actionsMeta, result inboxCountResult) {
+	if result.err != nil {
+		warning := nextactions.Warning{Source: nextactions.WarningSourceInbox, Detail: result.err.Error()}
+		meta.Warnings = append(meta.Warnings, warning)
+		return
+	}
+	meta.InboxCount = &result.count
+}
+
+// inbox returns the cards on the Inbox list, which accepts the same query parameters as actions, although the only
+// source its cards can have is the Inbox list
+func (s *server) inbox(w http.ResponseWriter, req *http.Request) {
+	fetch := listActions((*nextactions.Fetcher).FetchInbox)
+	s.serveActions(w, req, fetch, []string{nextactions.ActionSourceInboxList})
+}
+
+// someday returns the cards on the Someday/Maybe list, which accepts the same query parameters as actions, although
+// the only source its cards can have is the Someday/Maybe list
+func (s *server) someday(w http.ResponseWriter, req *http.Request) {
+	fetch := listActions((*nextactions.Fetcher).FetchSomeday)
+	s.serveActions(w, req, fetch, []string{nextactions.ActionSourceSomedayList})
+}
+
+// listActions returns an actionsFetch for a list that is fetched as plain actions, such as the Inbox
+func listActions(
+	fetch func(*nextactions.Fetcher, context.Context) ([]nextactions.Action, []nextactions.Warning, error),
+) actionsFetch {
+	return func(ctx context.Context, fetcher *nextactions.Fetcher, query *actionsQuery) (interface{}, actionsMeta, error) {
+		actions, warnings, err := fetch(fetcher, ctx)
+		if err != nil {
+			return nil, actionsMeta{}, err
+		}
+		return nextactions.FilterActions(actions, query.filter), actionsMeta{Warnings: warnings}, nil
+	}
+}
+
+// waiting returns the cards on the Waiting For list, which accepts the same query parameters as actions apart from
+// sort, although the only source its cards can have is the Waiting For list
+func (s *server) waiting(w http.ResponseWriter, req *http.Request) {
+	if _, ok := req.URL.Query()["sort"]; ok {
+		handleErrorWithStatus(w, http.StatusBadRequest, errors.New("sort is not supported, waiting items are "+
+			"always returned with those that have been waiting longest first"))
+		return
 	}
-	client := trello.Client{
-		Key:   cfg.TrelloKey,
-		Token: cfg.TrelloToken,
+	s.serveActions(w, req, fetchWaiting, []string{nextactions.ActionSourceWaitingForList})
+}
+
+func fetchWaiting(
+	ctx context.Context,
+	fetcher *nextactions.Fetcher,
+	query *actionsQuery,
+) (interface{}, actionsMeta, error) {
+	items, warnings, err := fetcher.FetchWaitingFor(ctx, time.Now())
+	if err != nil {
+		return nil, actionsMeta{}, err
 	}
 
-	fetcher := nextactions.Fetcher{Client: &client, Config: cfg}
+	filteredItems := make([]nextactions.WaitingItem, 0, len(items))
+	for i := range items {
+		if query.filter(&items[i].Action) {
+			filteredItems = append(filteredItems, items[i])
+		}
+	}
+	return filteredItems, actionsMeta{Warnings: warnings}, nil
+}
+
+// actionsFetch fetches the data for an endpoint that accepts the same query parameters as actions, filtered by the
+// query
+type actionsFetch func(
+	ctx context.Context,
+	fetcher *nextactions.Fetcher,
+	query *actionsQuery,
+) (interface{}, actionsMeta, error)
+
+// serveActions parses the query parameters and responds with whatever fetch returns, within the request timeout. The
+// sources are those of the actions fetch returns, which the source parameter is checked against. The fetcher fetch is
+// given is Limited, so that anything fetched at the same time shares the same limit on requests.
+func (s *server) serveActions(w http.ResponseWriter, req *http.Request, fetch actionsFetch, sources []string) {
+	query, err := parseActionsQuery(req.URL.Query(), time.Now(), sources)
+	if err != nil {
+		handleErrorWithStatus(w, http.StatusBadRequest, err)
+		return
+	}
+	fetcher := &nextactions.Fetcher{Client: s.client, Config: s.config(), Include: query.include, Sort: query.sort}
+
+	ctx, cancel := context.WithTimeout(req.Context(), s.cfg.RequestTimeout)
+	defer cancel()
+
+	if query.refresh {
+		ctx = trello.WithoutCache(ctx)
+	}
 
 	startTime := time.Now()
 
-	actions, err := fetcher.Fetch()
+	data, meta, err := fetch(ctx, fetcher.Limited(), query)
 	if err != nil {
 		handleError(w, err)
 		return
 	}
 
 	fmt.Printf("Finished API requests, took %s\n", time.Since(startTime))
+	for _, warning := range meta.Warnings {
+		fmt.Printf("Warning: %s\n", warning.Detail)
+	}
 
-	if err := json.NewEncoder(w).Encode(map[string][]nextactions.Action{"data": actions}); err != nil {
+	response := dataResponse{Data: data, Meta: meta}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		handleError(w, err)
 	}
 }
 
+func (s *server) completeAction(w http.ResponseWriter, req *http.Request) {
+	actionID, ok := completeActionID(req.URL.Path)
+	if !ok {
+		handleErrorWithStatus(w, http.StatusNotFound, fmt.Errorf("no route for %s", req.URL.Path))
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		handleErrorWithStatus(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", req.Method))
+		return
+	}
+
+	completer := nextactions.Completer{Client: s.client, Config: s.config(), ProjectCards: s.projectCards}
+
+	ctx, cancel := context.WithTimeout(req.Context(), s.cfg.RequestTimeout)
+	defer cancel()
+
+	if err := completer.Complete(ctx, actionID); err != nil {
+		if trello.IsNotFound(err) {
+			handleErrorWithStatus(w, http.StatusNotFound, fmt.Errorf("action %s not found", actionID))
+			return
+		}
+		handleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// check verifies the configuration against Trello and prints a report, returning whether every check passed
+func (s *server) check() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.RequestTimeout)
+	defer cancel()
+
+	checker := nextactions.Checker{Client: s.client, Config: s.config()}
+	report := checker.Check(ctx)
+	fmt.Print(report)
+
+	return report.Passed()
+}
+
+// completeActionID parses the action ID from a path of the form /actions/{id}/complete
+func completeActionID(urlPath string) (string, bool) {
+	parts := strings.Split(strings.Trim(urlPath, "/"), "/")
+	if len(parts) != 3 || parts[0] != "actions" || parts[1] == "" || parts[2] != "complete" {
+		return "", false
+	}
+	return parts[1], true
+}
+
 func main() {
-	http.HandleFunc("/actions", actions)
+	if len(os.Args) > 1 && os.Args[1] == "setup" {
+		if err := runSetup(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Setup failed: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	configFilePath := flag.String("config", "", "path to a YAML or TOML config file, overridden by environment variables")
+	checkOnly := flag.Bool("check", false, "check the configuration against Trello, print a report and exit")
+	flag.Parse()
+
+	cfg, err := config.Load(*configFilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not start: %s\n", err)
+		os.Exit(1)
+	}
+
+	s := newServer(cfg)
+	if err := s.resolveLists(); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not start: %s\n", err)
+		os.Exit(1)
+	}
+
+	if *checkOnly {
+		if !s.check() {
+			os.Exit(1)
+		}
+		return
+	}
+	if cfg.StartupCheck != config.StartupCheckOff && !s.check() && cfg.StartupCheck == config.StartupCheckFail {
+		fmt.Fprintln(os.Stderr, "Could not start: configuration check failed")
+		os.Exit(1)
+	}
+
+	go s.lists.ResolvePeriodically(context.Background(), cfg.TrelloListRefreshInterval)
+
+	http.HandleFunc("/actions", s.actions)
+	http.HandleFunc("/actions/", s.completeAction)
+	http.HandleFunc("/waiting", s.waiting)
+	http.HandleFunc("/inbox", s.inbox)
+	http.HandleFunc("/someday", s.someday)
 
 	fmt.Println("Listening on port 8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
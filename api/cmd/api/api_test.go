@@ -7,55 +7,100 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"path"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stevecshanks/next-actions-in-go/api/internal/config"
+	"github.com/stevecshanks/next-actions-in-go/api/internal/nextactions"
 	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
 )
 
-func trelloResponse(fileName string) string {
-	return path.Join("../../internal/trello/testdata", fileName)
+func testConfig() *config.Config {
+	return &config.Config{
+		TrelloKey:                   "some key",
+		TrelloToken:                 "some token",
+		TrelloNextActionsListID:     "nextActionsList123",
+		TrelloProjectsListID:        "projectsList456",
+		TrelloTodoListNames:         config.DefaultTrelloTodoListNames(),
+		TrelloActionsPerProject:     config.DefaultTrelloActionsPerProject,
+		TrelloBlockedLabelNames:     config.DefaultTrelloBlockedLabelNames(),
+		TrelloMaxConcurrentRequests: config.DefaultTrelloMaxConcurrentRequests,
+		RequestTimeout:              config.DefaultRequestTimeout,
+	}
+}
+
+// fakeTrello is a stateful Trello server for end-to-end tests, with the Next Actions and Projects lists on an inbox
+// board, and a config that points the server at them
+type fakeTrello struct {
+	*trello.FakeServer
+	cfg               *config.Config
+	boardID           string
+	nextActionsListID string
+	projectsListID    string
+}
+
+func newFakeTrello(t *testing.T) *fakeTrello {
+	fake := trello.NewFakeServer("some key", "some token")
+	t.Cleanup(fake.Close)
+
+	boardID := fake.AddBoard(trello.FakeBoard{Name: "Inbox"})
+	nextActionsListID := fake.AddList(trello.FakeList{BoardID: boardID, Name: "Next Actions"})
+	projectsListID := fake.AddList(trello.FakeList{BoardID: boardID, Name: "Projects"})
+
+	cfg := testConfig()
+	cfg.TrelloNextActionsListID = nextActionsListID
+	cfg.TrelloProjectsListID = projectsListID
+	cfg.TrelloAPIBaseURL = fake.URL()
+
+	return &fakeTrello{
+		FakeServer:        fake,
+		cfg:               cfg,
+		boardID:           boardID,
+		nextActionsListID: nextActionsListID,
+		projectsListID:    projectsListID,
+	}
 }
 
 func TestActions(t *testing.T) {
-	mockServer := trello.CreateMockServer("some key", "some token")
-	defer trello.TeardownMockServer()
+	fake := newFakeTrello(t)
 
-	mockServer.AddFileResponse(trello.OwnedCardsPath(), trelloResponse("my_cards_response.json"))
-	mockServer.AddFileResponse(
-		trello.CardsOnListPath("nextActionsList123"),
-		trelloResponse("next_actions_list_response.json"),
-	)
-	mockServer.AddFileResponse(
-		trello.CardsOnListPath("projectsList456"),
-		trelloResponse("projects_list_response.json"),
-	)
-	mockServer.AddFileResponse(
-		trello.ListsOnBoardPath("projectBoard789"),
-		trelloResponse("board_lists_response.json"),
-	)
-	mockServer.AddFileResponse(
-		trello.CardsOnListPath("todoListId"),
-		trelloResponse("project_todo_list_cards_response.json"),
-	)
-	mockServer.AddFileResponse(
-		trello.BoardPath("myBoardId"),
-		trelloResponse("board_response.json"),
-	)
-	mockServer.AddFileResponse(
-		trello.BoardPath("boardWithNoImagesId"),
-		trelloResponse("board_with_no_images_response.json"),
-	)
+	myProjectBoardID := fake.AddBoard(trello.FakeBoard{
+		Name: "My Project",
+		BackgroundImageURLs: []string{
+			"https://trello-backgrounds.s3.amazonaws.com/SharedBackground/75x100.jpg",
+			"https://trello-backgrounds.s3.amazonaws.com/SharedBackground/144x192.jpg",
+		},
+	})
+	doingListID := fake.AddList(trello.FakeList{BoardID: myProjectBoardID, Name: "Doing"})
+	firstDueBy, _ := time.Parse(time.RFC3339, "2020-01-01T10:30:00Z")
+	fake.AddOwnedCard(trello.FakeCard{ListID: doingListID, Name: "My First Action", Due: &firstDueBy})
+	fake.AddOwnedCard(trello.FakeCard{ListID: doingListID, Name: "My Second Action"})
+
+	officeLabelID := fake.AddLabel(trello.FakeLabel{BoardID: fake.boardID, Name: "@office", Color: "green"})
+	todoDueBy, _ := time.Parse(time.RFC3339, "2020-01-15T10:29:59Z")
+	fake.AddCard(trello.FakeCard{
+		ListID:   fake.nextActionsListID,
+		Name:     "Todo Action",
+		Due:      &todoDueBy,
+		LabelIDs: []string{officeLabelID},
+	})
 
-	config.SetupEnvironment("some key", "some token", "nextActionsList123", "projectsList456")
-	defer config.TeardownEnvironment()
+	anotherProjectBoardID := fake.AddBoard(trello.FakeBoard{Name: "Another Project"})
+	anotherProjectBoard, _ := fake.Board(anotherProjectBoardID)
+	fake.AddCard(trello.FakeCard{
+		ListID: fake.projectsListID,
+		Name:   trello.BoardBaseURL + anotherProjectBoard.ShortLink + "/another-project",
+	})
+	todoListID := fake.AddList(trello.FakeList{BoardID: anotherProjectBoardID, Name: "Todo"})
+	fake.AddCard(trello.FakeCard{ListID: todoListID, Name: "Project Action"})
 
 	req, err := http.NewRequest("GET", "/actions", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(actions)
+	handler := http.HandlerFunc(newServer(fake.cfg).actions)
 
 	handler.ServeHTTP(rr, req)
 
@@ -66,19 +111,65 @@ func TestActions(t *testing.T) {
 	assertResponseMatchesContractFile(t, rr.Body.Bytes(), "api_success_response.json")
 }
 
+func TestActionsRefreshBypassesCache(t *testing.T) {
+	fake := newFakeTrello(t)
+	fake.AddCard(trello.FakeCard{ListID: fake.nextActionsListID, Name: "First"})
+	s := newServer(fake.cfg)
+
+	getActionNames := func(url string) []string {
+		req := httptest.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(s.actions).ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("%s returned status: %v", url, status)
+		}
+		var response struct {
+			Data []struct {
+				Name string `json:"name"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Could not parse response as JSON: %s", err)
+		}
+		names := make([]string, 0, len(response.Data))
+		for _, action := range response.Data {
+			names = append(names, action.Name)
+		}
+		return names
+	}
+
+	getActionNames("/actions")
+	requestCount := fake.RequestCount()
+	fake.AddCard(trello.FakeCard{ListID: fake.nextActionsListID, Name: "Second"})
+
+	if names := getActionNames("/actions"); len(names) != 1 {
+		t.Errorf("Expected the cached action only, got %v", names)
+	}
+	if fake.RequestCount() != requestCount {
+		t.Errorf("Expected cached request to make no Trello requests, made %d", fake.RequestCount()-requestCount)
+	}
+
+	if names := getActionNames("/actions?refresh=true"); len(names) != 2 {
+		t.Errorf("Expected both actions after refreshing, got %v", names)
+	}
+	if fake.RequestCount() == requestCount {
+		t.Error("Expected refresh request to make Trello requests, made none")
+	}
+}
+
 func TestActionsErrors(t *testing.T) {
-	trello.CreateMockServer("some key", "some token")
-	defer trello.TeardownMockServer()
+	fake := newFakeTrello(t)
 
-	config.SetupEnvironment("some key", "some token", "nextActionsList123", "projectsList456")
-	defer config.TeardownEnvironment()
+	// Only the owned cards request fails, the others are made concurrently so must succeed for a predictable error
+	fake.InjectErrors(trello.OwnedCardsPath(), nil, http.StatusNotFound)
 
 	req, err := http.NewRequest("GET", "/actions", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(actions)
+	handler := http.HandlerFunc(newServer(fake.cfg).actions)
 
 	handler.ServeHTTP(rr, req)
 
@@ -89,6 +180,388 @@ func TestActionsErrors(t *testing.T) {
 	assertResponseMatchesContractFile(t, rr.Body.Bytes(), "api_error_response.json")
 }
 
+func TestActionsRejectsUnknownInclude(t *testing.T) {
+	req, err := http.NewRequest("GET", "/actions?include=archived,deleted", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(newServer(testConfig()).actions)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("/actions?include=archived,deleted returned status: %v", status)
+	}
+	if !strings.Contains(rr.Body.String(), `cannot include \"deleted\"`) {
+		t.Errorf("Expected the unknown value to be reported, got %s", rr.Body.String())
+	}
+}
+
+func TestActionsRejectsUnknownSort(t *testing.T) {
+	req, err := http.NewRequest("GET", "/actions?sort=name", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(newServer(testConfig()).actions)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("/actions?sort=name returned status: %v", status)
+	}
+	if !strings.Contains(rr.Body.String(), `cannot sort by \"name\"`) {
+		t.Errorf("Expected the unknown sort to be reported, got %s", rr.Body.String())
+	}
+}
+
+func TestCompleteAction(t *testing.T) {
+	fake := newFakeTrello(t)
+
+	projectBoardID := fake.AddBoard(trello.FakeBoard{Name: "My Project"})
+	doingListID := fake.AddList(trello.FakeList{BoardID: projectBoardID, Name: "Doing"})
+	cardID := fake.AddOwnedCard(trello.FakeCard{ListID: doingListID, Name: "My First Action"})
+
+	req, err := http.NewRequest("POST", "/actions/"+cardID+"/complete", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(newServer(fake.cfg).completeAction)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("/actions/%s/complete returned status: %v", cardID, status)
+	}
+	if card, _ := fake.Card(cardID); !card.DueComplete {
+		t.Errorf("Expected card to be marked due complete, got %+v", card)
+	}
+}
+
+func TestCompleteActionNotFound(t *testing.T) {
+	fake := newFakeTrello(t)
+
+	req, err := http.NewRequest("POST", "/actions/missingCardId/complete", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(newServer(fake.cfg).completeAction)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("/actions/missingCardId/complete returned status: %v", status)
+	}
+}
+
+func TestCompleteActionRequiresPost(t *testing.T) {
+	req, err := http.NewRequest("GET", "/actions/todoCardId/complete", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(newServer(testConfig()).completeAction)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("GET /actions/todoCardId/complete returned status: %v", status)
+	}
+}
+
+func TestCompleteActionUnknownRoute(t *testing.T) {
+	req, err := http.NewRequest("POST", "/actions/todoCardId/something-else", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(newServer(testConfig()).completeAction)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("/actions/todoCardId/something-else returned status: %v", status)
+	}
+}
+
+func TestActionsAndCompleteAgainstFakeTrello(t *testing.T) {
+	fake := newFakeTrello(t)
+	cardID := fake.AddCard(trello.FakeCard{ListID: fake.nextActionsListID, Name: "Next Action"})
+
+	s := newServer(fake.cfg)
+
+	req := httptest.NewRequest("POST", "/actions/"+cardID+"/complete", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.completeAction).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("/actions/%s/complete returned status: %v", cardID, status)
+	}
+	if card, _ := fake.Card(cardID); !card.Closed {
+		t.Errorf("Expected card %s to be archived", cardID)
+	}
+
+	req = httptest.NewRequest("GET", "/actions", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(s.actions).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("/actions returned status: %v", status)
+	}
+	var response struct {
+		Data []interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response as JSON: %s", err)
+	}
+	if len(response.Data) != 0 {
+		t.Errorf("Expected no actions after completing the only one, got %+v", response.Data)
+	}
+}
+
+func TestActionsFilteredByContextAgainstFakeTrello(t *testing.T) {
+	fake := newFakeTrello(t)
+	officeLabelID := fake.AddLabel(trello.FakeLabel{BoardID: fake.boardID, Name: "@office", Color: "green"})
+	homeLabelID := fake.AddLabel(trello.FakeLabel{BoardID: fake.boardID, Name: "@home", Color: "blue"})
+	officeCardID := fake.AddCard(
+		trello.FakeCard{ListID: fake.nextActionsListID, Name: "Office", LabelIDs: []string{officeLabelID}},
+	)
+	fake.AddCard(trello.FakeCard{ListID: fake.nextActionsListID, Name: "Home", LabelIDs: []string{homeLabelID}})
+
+	req := httptest.NewRequest("GET", "/actions?context=@office", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(newServer(fake.cfg).actions).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("/actions?context=@office returned status: %v", status)
+	}
+	var response struct {
+		Data []struct {
+			ID     string `json:"id"`
+			Labels []struct {
+				Name  string `json:"name"`
+				Color string `json:"color"`
+			} `json:"labels"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response as JSON: %s", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].ID != officeCardID ||
+		len(response.Data[0].Labels) != 1 || response.Data[0].Labels[0].Color != "green" {
+		t.Errorf("Expected only the @office action with its label, got %+v", response.Data)
+	}
+}
+
+func TestWaitingAgainstFakeTrello(t *testing.T) {
+	fake := newFakeTrello(t)
+	waitingForListID := fake.AddList(trello.FakeList{BoardID: fake.boardID, Name: "Waiting For"})
+	lastActivity := time.Now().Add(-50 * time.Hour)
+	cardID := fake.AddCard(trello.FakeCard{ListID: waitingForListID, Name: "Reply", DateLastActivity: lastActivity})
+	fake.cfg.TrelloWaitingForListID = waitingForListID
+	fake.cfg.TrelloWaitingForNudgeDays = 2
+
+	req := httptest.NewRequest("GET", "/waiting", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(newServer(fake.cfg).waiting).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("/waiting returned status: %v", status)
+	}
+	var response struct {
+		Data []struct {
+			Type        string `json:"type"`
+			ID          string `json:"id"`
+			ProjectName string `json:"projectName"`
+			WaitingDays int    `json:"waitingDays"`
+			Nudge       bool   `json:"nudge"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response as JSON: %s", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].ID != cardID || response.Data[0].Type != "waitingItems" ||
+		response.Data[0].ProjectName != "Inbox" || response.Data[0].WaitingDays != 2 || !response.Data[0].Nudge {
+		t.Errorf("Expected the card to have been waiting 2 days and need a nudge, got %+v", response.Data)
+	}
+}
+
+func TestWaitingRejectsSort(t *testing.T) {
+	cfg := testConfig()
+	cfg.TrelloWaitingForListID = "waitingForList789"
+
+	req := httptest.NewRequest("GET", "/waiting?sort=due", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(newServer(cfg).waiting).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("/waiting returned status: %v", status)
+	}
+	if !strings.Contains(rr.Body.String(), "sort is not supported") {
+		t.Errorf("Expected error about sort, got %s", rr.Body.String())
+	}
+}
+
+func TestWaitingNotFoundWithoutWaitingForList(t *testing.T) {
+	req := httptest.NewRequest("GET", "/waiting", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(newServer(testConfig()).waiting).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("/waiting returned status: %v", status)
+	}
+}
+
+func TestInboxAndActionsInboxCountAgainstFakeTrello(t *testing.T) {
+	fake := newFakeTrello(t)
+	inboxListID := fake.AddList(trello.FakeList{BoardID: fake.boardID, Name: "Inbox"})
+	cardID := fake.AddCard(trello.FakeCard{ListID: inboxListID, Name: "Unprocessed"})
+	fake.cfg.TrelloInboxListID = inboxListID
+
+	s := newServer(fake.cfg)
+
+	req := httptest.NewRequest("GET", "/inbox", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.inbox).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("/inbox returned status: %v", status)
+	}
+	var inboxResponse struct {
+		Data []struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &inboxResponse); err != nil {
+		t.Fatalf("Could not parse response as JSON: %s", err)
+	}
+	if len(inboxResponse.Data) != 1 || inboxResponse.Data[0].ID != cardID || inboxResponse.Data[0].Type != "actions" {
+		t.Errorf("Expected the Inbox card as an action, got %+v", inboxResponse.Data)
+	}
+
+	req = httptest.NewRequest("GET", "/inbox?source=inboxList", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(s.inbox).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK || !strings.Contains(rr.Body.String(), cardID) {
+		t.Errorf("Expected /inbox?source=inboxList to return the Inbox card, got %v: %s", status, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/inbox?source=owned", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(s.inbox).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "expected inboxList") {
+		t.Errorf("Expected /inbox?source=owned to be rejected, got %v: %s", status, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/actions", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(s.actions).ServeHTTP(rr, req)
+
+	var actionsResponse struct {
+		Meta struct {
+			InboxCount *int `json:"inboxCount"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &actionsResponse); err != nil {
+		t.Fatalf("Could not parse response as JSON: %s", err)
+	}
+	if actionsResponse.Meta.InboxCount == nil || *actionsResponse.Meta.InboxCount != 1 {
+		t.Errorf("Expected an inbox count of 1, got %s", rr.Body.String())
+	}
+}
+
+func TestActionsWarnsIfInboxCannotBeCounted(t *testing.T) {
+	fake := newFakeTrello(t)
+	fake.cfg.TrelloInboxListID = "missingInboxListId"
+
+	req := httptest.NewRequest("GET", "/actions", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(newServer(fake.cfg).actions).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("/actions returned status: %v", status)
+	}
+	var response struct {
+		Meta struct {
+			Warnings   []nextactions.Warning `json:"warnings"`
+			InboxCount *int                  `json:"inboxCount"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response as JSON: %s", err)
+	}
+	warnings := response.Meta.Warnings
+	if response.Meta.InboxCount != nil || len(warnings) != 1 || warnings[0].Source != nextactions.WarningSourceInbox {
+		t.Errorf("Expected an inbox warning instead of a count, got %s", rr.Body.String())
+	}
+}
+
+func TestSomedayNotFoundWithoutSomedayList(t *testing.T) {
+	req := httptest.NewRequest("GET", "/someday", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(newServer(testConfig()).someday).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("/someday returned status: %v", status)
+	}
+}
+
+func TestActionsWithListsConfiguredByName(t *testing.T) {
+	fake := newFakeTrello(t)
+	fake.AddCard(trello.FakeCard{ListID: fake.nextActionsListID, Name: "Next Action"})
+	cfg := fake.cfg
+	cfg.TrelloNextActionsListID = "Inbox/Next Actions"
+	cfg.TrelloProjectsListID = "Inbox/Projects"
+
+	s := newServer(cfg)
+	if err := s.resolveLists(); err != nil {
+		t.Fatalf("Could not resolve lists: %s", err)
+	}
+
+	req := httptest.NewRequest("GET", "/actions", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.actions).ServeHTTP(rr, req)
+
+	var response struct {
+		Data []struct {
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not parse response as JSON: %s", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].Name != "Next Action" {
+		t.Errorf("Expected the action on the Next Actions list, got %s", rr.Body.String())
+	}
+
+	cfg.TrelloProjectsListID = "Inbox/Missing"
+	if err := newServer(cfg).resolveLists(); err == nil {
+		t.Error("Expected an error resolving a missing list")
+	}
+}
+
+func TestCheckAgainstFakeTrello(t *testing.T) {
+	fake := newFakeTrello(t)
+	cfg := fake.cfg
+	cfg.TrelloProjectsListID = "missingProjectsListId"
+
+	if newServer(cfg).check() {
+		t.Error("Expected check to fail with missing projects list")
+	}
+
+	cfg.TrelloProjectsListID = fake.projectsListID
+
+	if !newServer(cfg).check() {
+		t.Error("Expected check to pass")
+	}
+}
+
 func assertResponseMatchesContractFile(t *testing.T, response []byte, fileName string) {
 	expectedBytes, err := ioutil.ReadFile(path.Join("../../../contracts", fileName))
 	if err != nil {
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/nextactions"
+)
+
+// actionsQuery holds the options given in the query string of a request for actions
+type actionsQuery struct {
+	include nextactions.Include
+	sort    nextactions.Sort
+	filter  nextactions.Filter
+	refresh bool
+}
+
+// parseActionsQuery parses the query string of a request to an endpoint that returns actions from the given sources,
+// which are the only values allowed for source
+func parseActionsQuery(query url.Values, now time.Time, sources []string) (*actionsQuery, error) {
+	// ?include=archived,completed,templates returns cards that would otherwise be hidden, e.g. to audit a board
+	include, err := nextactions.ParseInclude(query.Get("include"))
+	if err != nil {
+		return nil, err
+	}
+	// ?sort=due|project|position chooses the order of the actions, the default puts the most urgent first
+	sortBy, err := nextactions.ParseSort(query.Get("sort"))
+	if err != nil {
+		return nil, err
+	}
+	filters, err := parseFilters(query, now, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	return &actionsQuery{
+		include: include,
+		sort:    sortBy,
+		filter:  nextactions.All(filters...),
+		// ?refresh=true shows changes just made in Trello without waiting for the cache to expire
+		refresh: query.Get("refresh") == "true",
+	}, nil
+}
+
+// parseFilters returns a filter for each filtering parameter in the query. Parameters that can be repeated allow
+// actions matching any of their values, except for label where actions must have every label.
+func parseFilters(query url.Values, now time.Time, allowedSources []string) ([]nextactions.Filter, error) {
+	filters := make([]nextactions.Filter, 0)
+
+	// ?context=@office shows only the actions that can be done there
+	if contexts := query["context"]; len(contexts) > 0 {
+		filters = append(filters, anyValue(contexts, nextactions.WithLabel))
+	}
+	if projects := query["project"]; len(projects) > 0 {
+		filters = append(filters, anyValue(projects, nextactions.InProject))
+	}
+	for _, label := range query["label"] {
+		filters = append(filters, nextactions.WithLabel(label))
+	}
+	if sources := query["source"]; len(sources) > 0 {
+		for _, source := range sources {
+			if !containsString(allowedSources, source) {
+				return nil, fmt.Errorf("invalid source %q, expected %s", source, describeValues(allowedSources))
+			}
+		}
+		filters = append(filters, anyValue(sources, nextactions.FromSource))
+	}
+	if text := query.Get("q"); text != "" {
+		filters = append(filters, nextactions.NameContains(text))
+	}
+
+	dueFilters, err := parseDueFilters(query, now)
+	if err != nil {
+		return nil, err
+	}
+	return append(filters, dueFilters...), nil
+}
+
+func parseDueFilters(query url.Values, now time.Time) ([]nextactions.Filter, error) {
+	filters := make([]nextactions.Filter, 0)
+
+	if value := query.Get("dueBefore"); value != "" {
+		t, err := parseQueryTime("dueBefore", value)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, nextactions.DueBefore(t))
+	}
+	if value := query.Get("dueAfter"); value != "" {
+		t, err := parseQueryTime("dueAfter", value)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, nextactions.DueAfter(t))
+	}
+	if value := query.Get("overdue"); value != "" {
+		overdue, err := parseQueryBool("overdue", value)
+		if err != nil {
+			return nil, err
+		}
+		if overdue {
+			filters = append(filters, nextactions.Overdue(now))
+		} else {
+			filters = append(filters, nextactions.Not(nextactions.Overdue(now)))
+		}
+	}
+	if value := query.Get("hasDue"); value != "" {
+		hasDue, err := parseQueryBool("hasDue", value)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, nextactions.HasDueDate(hasDue))
+	}
+	return filters, nil
+}
+
+func anyValue(values []string, filterFor func(value string) nextactions.Filter) nextactions.Filter {
+	filters := make([]nextactions.Filter, len(values))
+	for i, value := range values {
+		filters[i] = filterFor(value)
+	}
+	return nextactions.Any(filters...)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// describeValues lists the values for an error message, e.g. "one of owned, nextActionsList or projectTodo"
+func describeValues(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	last := len(values) - 1
+	return "one of " + strings.Join(values[:last], ", ") + " or " + values[last]
+}
+
+// parseQueryTime accepts either a full RFC 3339 time or a date, which is taken to be midnight UTC
+func parseQueryTime(name, value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, expected a date such as 2020-01-31 or an RFC 3339 time", name, value)
+	}
+	return t, nil
+}
+
+func parseQueryBool(name, value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q, expected true or false", name, value)
+	}
+	return b, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/nextactions"
+)
+
+func actionSources() []string {
+	return []string{
+		nextactions.ActionSourceOwned,
+		nextactions.ActionSourceNextActionsList,
+		nextactions.ActionSourceProjectTodo,
+	}
+}
+
+func TestParseActionsQueryFilters(t *testing.T) {
+	now := time.Date(2020, 2, 12, 16, 24, 0, 0, time.UTC)
+	dueBy := now.Add(-time.Hour)
+	actions := []nextactions.Action{
+		{ID: "match", Name: "Call Bob", DueBy: &dueBy, ProjectName: "Home", Sources: []string{"owned"},
+			Labels: []nextactions.Label{{Name: "@phone"}, {Name: "urgent"}}},
+		{ID: "other project", Name: "Call Bob", DueBy: &dueBy, ProjectName: "Work", Sources: []string{"owned"},
+			Labels: []nextactions.Label{{Name: "@phone"}, {Name: "urgent"}}},
+		{ID: "missing label", Name: "Call Bob", DueBy: &dueBy, ProjectName: "Home", Sources: []string{"owned"},
+			Labels: []nextactions.Label{{Name: "@phone"}}},
+		{ID: "no due date", Name: "Call Bob", ProjectName: "Home", Sources: []string{"owned"},
+			Labels: []nextactions.Label{{Name: "@phone"}, {Name: "urgent"}}},
+	}
+
+	query, err := url.ParseQuery(
+		"context=@phone&context=@office&project=home&project=garden&label=@phone&label=urgent&source=owned" +
+			"&q=bob&dueBefore=2020-02-13&dueAfter=2020-02-01T00:00:00Z&overdue=true&hasDue=true&refresh=true",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actionsQuery, err := parseActionsQuery(query, now, actionSources())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	filtered := nextactions.FilterActions(actions, actionsQuery.filter)
+	if len(filtered) != 1 || filtered[0].ID != "match" {
+		t.Errorf("Expected only the matching action, got %+v", filtered)
+	}
+	if !actionsQuery.refresh || actionsQuery.sort != nextactions.SortSmart {
+		t.Errorf("Expected refresh and the default sort, got %+v", actionsQuery)
+	}
+}
+
+func TestParseActionsQueryRejectsInvalidValues(t *testing.T) {
+	testCases := map[string]string{
+		"source=inbox":         `invalid source "inbox", expected one of owned, nextActionsList or projectTodo`,
+		"dueBefore=tomorrow":   `invalid dueBefore "tomorrow", expected a date such as 2020-01-31 or an RFC 3339 time`,
+		"overdue=yes%20please": `invalid overdue "yes please", expected true or false`,
+		"hasDue=maybe":         `invalid hasDue "maybe", expected true or false`,
+		"sort=name":            `cannot sort by "name", expected one of smart, due, project or position`,
+	}
+	for rawQuery, expectedError := range testCases {
+		query, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseActionsQuery(query, time.Now(), actionSources()); err == nil || err.Error() != expectedError {
+			t.Errorf("Expected error %q for %s, got %v", expectedError, rawQuery, err)
+		}
+	}
+}
+
+func TestParseActionsQueryOnlyAllowsGivenSources(t *testing.T) {
+	sources := []string{nextactions.ActionSourceInboxList}
+
+	query := url.Values{"source": {nextactions.ActionSourceInboxList}}
+	if _, err := parseActionsQuery(query, time.Now(), sources); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	query = url.Values{"source": {nextactions.ActionSourceOwned}}
+	expectedError := `invalid source "owned", expected inboxList`
+	if _, err := parseActionsQuery(query, time.Now(), sources); err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %v", expectedError, err)
+	}
+}
@@ -0,0 +1,253 @@
+package main // nolint:golint // package comment is in another file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stevecshanks/next-actions-in-go/api/internal/config"
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+	"gopkg.in/yaml.v2"
+)
+
+// setupOptions are the command line options of the setup command
+type setupOptions struct {
+	configFilePath  string
+	nextActionsList string
+	projectsList    string
+	doneList        string
+	waitingForList  string
+	inboxList       string
+	somedayList     string
+	outputPath      string
+	force           bool
+}
+
+// secretCounterparts maps each secret setting to the setting that reads it from a file, and back again, as only one of
+// each pair may be set
+func secretCounterparts() map[string]string {
+	return map[string]string{
+		"TRELLO_KEY":        "TRELLO_KEY_FILE",
+		"TRELLO_KEY_FILE":   "TRELLO_KEY",
+		"TRELLO_TOKEN":      "TRELLO_TOKEN_FILE",
+		"TRELLO_TOKEN_FILE": "TRELLO_TOKEN",
+	}
+}
+
+// runSetup lists the user's boards and lists or, if lists have been chosen by name, writes a configuration using them.
+// It needs only the Trello key and token to have been configured.
+func runSetup(args []string, stdout io.Writer) error {
+	options, err := parseSetupOptions(args, stdout)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := config.LoadCredentials(options.configFilePath)
+	if err != nil {
+		return err
+	}
+
+	clientOptions := make([]trello.ClientOption, 0)
+	if credentials.TrelloAPIBaseURL != "" {
+		clientOptions = append(clientOptions, trello.WithBaseURL(credentials.TrelloAPIBaseURL))
+	}
+	client := trello.NewClient(credentials.TrelloKey, credentials.TrelloToken, clientOptions...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), trello.DefaultHTTPTimeout)
+	defer cancel()
+
+	directory, err := client.FetchDirectory(ctx)
+	if err != nil {
+		return fmt.Errorf("could not fetch boards: %w", err)
+	}
+
+	if options.nextActionsList == "" && options.projectsList == "" {
+		printDirectory(stdout, directory)
+		return nil
+	}
+	if options.nextActionsList == "" || options.projectsList == "" {
+		return errors.New("both --next-actions and --projects are needed to write a configuration")
+	}
+
+	values, err := chosenListIDs(directory, options)
+	if err != nil {
+		return err
+	}
+	for name, value := range credentials.Settings {
+		values[name] = value
+	}
+
+	if err := writeSetupOutput(options, values); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Wrote configuration to %s\n", options.outputPath)
+	return nil
+}
+
+func parseSetupOptions(args []string, stdout io.Writer) (*setupOptions, error) {
+	options := &setupOptions{}
+
+	flags := flag.NewFlagSet("setup", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	configUsage := "path to a YAML or TOML config file to read the key and token from"
+	flags.StringVar(&options.configFilePath, "config", "", configUsage)
+	flags.StringVar(&options.nextActionsList, "next-actions", "", `the Next Actions list, as "board name/list name"`)
+	flags.StringVar(&options.projectsList, "projects", "", `the Projects list, as "board name/list name"`)
+	flags.StringVar(&options.doneList, "done", "", `the optional Done list, as "board name/list name"`)
+	waitingForUsage := `the optional Waiting For list, as "board name/list name"`
+	flags.StringVar(&options.waitingForList, "waiting-for", "", waitingForUsage)
+	flags.StringVar(&options.inboxList, "inbox", "", `the optional Inbox list, as "board name/list name"`)
+	flags.StringVar(&options.somedayList, "someday", "", `the optional Someday/Maybe list, as "board name/list name"`)
+	flags.StringVar(&options.outputPath, "output", ".env", "the .env, YAML or TOML file to write the configuration to")
+	flags.BoolVar(&options.force, "force", false, "overwrite an existing YAML or TOML file")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	return options, nil
+}
+
+// printDirectory lists every board and list along with their IDs, so the user can choose which lists to use
+func printDirectory(w io.Writer, directory *trello.Directory) {
+	for _, board := range directory.Boards {
+		fmt.Fprintf(w, "%s\n", board.Name)
+		for _, list := range directory.ListsByBoardID[board.ID] {
+			fmt.Fprintf(w, "  %s (%s)\n", list.Name, list.ID)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run setup again with --next-actions "board name/list name" and --projects "board name/list name"`)
+	fmt.Fprintln(w, "to write a configuration using those lists")
+}
+
+// chosenListIDs finds the IDs of the lists chosen by name, keyed by the settings they are used for
+func chosenListIDs(directory *trello.Directory, options *setupOptions) (map[string]string, error) {
+	references := map[string]string{
+		"TRELLO_NEXT_ACTIONS_LIST_ID": options.nextActionsList,
+		"TRELLO_PROJECTS_LIST_ID":     options.projectsList,
+		"TRELLO_DONE_LIST_ID":         options.doneList,
+		"TRELLO_WAITING_FOR_LIST_ID":  options.waitingForList,
+		"TRELLO_INBOX_LIST_ID":        options.inboxList,
+		"TRELLO_SOMEDAY_LIST_ID":      options.somedayList,
+	}
+
+	values := make(map[string]string)
+	problems := make([]string, 0)
+	for name, reference := range references {
+		if reference == "" {
+			continue
+		}
+		list, err := directory.FindList(reference)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		values[name] = list.ID
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return values, nil
+}
+
+func writeSetupOutput(options *setupOptions, values map[string]string) error {
+	switch strings.ToLower(filepath.Ext(options.outputPath)) {
+	case ".yaml", ".yml", ".toml":
+		return writeConfigFile(options.outputPath, options.force, values)
+	default:
+		return writeEnvFile(options.outputPath, values)
+	}
+}
+
+// writeConfigFile writes the values to a new YAML or TOML config file, using the lower case setting names
+func writeConfigFile(filePath string, force bool, values map[string]string) error {
+	if _, err := os.Stat(filePath); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", filePath)
+	}
+
+	fileValues := make(map[string]string)
+	for name, value := range values {
+		fileValues[strings.ToLower(name)] = value
+	}
+
+	var contents []byte
+	if strings.ToLower(filepath.Ext(filePath)) == ".toml" {
+		var buffer bytes.Buffer
+		if err := toml.NewEncoder(&buffer).Encode(fileValues); err != nil {
+			return err
+		}
+		contents = buffer.Bytes()
+	} else {
+		var err error
+		if contents, err = yaml.Marshal(fileValues); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(filePath, contents, 0600)
+}
+
+// writeEnvFile sets the values in a .env file, keeping any other lines already in it. If a secret is being set, any
+// line setting the other way of giving that secret is removed, as only one of them may be used.
+func writeEnvFile(filePath string, values map[string]string) error {
+	existing, err := ioutil.ReadFile(filePath) // nolint:gosec // the output file is chosen by whoever runs setup
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	removed := make(map[string]bool)
+	for name := range values {
+		if counterpart, ok := secretCounterparts()[name]; ok && values[counterpart] == "" {
+			removed[counterpart] = true
+		}
+	}
+
+	lines := make([]string, 0)
+	written := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(existing))
+	for scanner.Scan() {
+		line := scanner.Text()
+		name := strings.TrimSpace(strings.SplitN(line, "=", 2)[0])
+		switch {
+		case removed[name]:
+			continue
+		case values[name] != "" && !written[name]:
+			line = name + "=" + values[name]
+			written[name] = true
+		case values[name] != "":
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if !written[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, name+"="+values[name])
+	}
+
+	return ioutil.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
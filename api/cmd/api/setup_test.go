@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/config"
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+type setupFixture struct {
+	fake              *trello.FakeServer
+	nextActionsListID string
+	projectsListID    string
+	doneListID        string
+	directory         string
+}
+
+func newSetupFixture(t *testing.T) *setupFixture {
+	fake := trello.NewFakeServer("some key", "some token")
+
+	boardID := fake.AddBoard(trello.FakeBoard{Name: "GTD"})
+	fixture := &setupFixture{
+		fake:              fake,
+		nextActionsListID: fake.AddList(trello.FakeList{BoardID: boardID, Name: "Next Actions"}),
+		projectsListID:    fake.AddList(trello.FakeList{BoardID: boardID, Name: "Projects"}),
+		doneListID:        fake.AddList(trello.FakeList{BoardID: boardID, Name: "Done"}),
+	}
+
+	directory, err := ioutil.TempDir("", "setup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture.directory = directory
+
+	config.SetupEnvironment("some key", "some token", "", "")
+	os.Setenv("TRELLO_API_BASE_URL", fake.URL())
+
+	return fixture
+}
+
+func (f *setupFixture) close() {
+	config.TeardownEnvironment()
+	os.Setenv("TRELLO_API_BASE_URL", "")
+	os.RemoveAll(f.directory)
+	f.fake.Close()
+}
+
+func TestSetupListsBoardsAndLists(t *testing.T) {
+	fixture := newSetupFixture(t)
+	defer fixture.close()
+
+	var output bytes.Buffer
+	if err := runSetup([]string{}, &output); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLine := "  Next Actions (" + fixture.nextActionsListID + ")"
+	if !strings.HasPrefix(output.String(), "GTD\n") || !strings.Contains(output.String(), expectedLine) {
+		t.Errorf("Expected boards and lists to be listed, got %s", output.String())
+	}
+}
+
+func TestSetupWritesEnvFile(t *testing.T) {
+	fixture := newSetupFixture(t)
+	defer fixture.close()
+
+	envFilePath := filepath.Join(fixture.directory, ".env")
+	existing := "# My settings\nTRELLO_KEY_FILE=/run/secrets/key\nTRELLO_PROJECTS_LIST_ID=old\nREQUEST_TIMEOUT=5s\n"
+	if err := ioutil.WriteFile(envFilePath, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"--next-actions", "GTD/Next Actions", "--projects", "gtd / projects", "--output", envFilePath}
+	if err := runSetup(args, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := ioutil.ReadFile(envFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedContents := "# My settings\n" +
+		"TRELLO_PROJECTS_LIST_ID=" + fixture.projectsListID + "\n" +
+		"REQUEST_TIMEOUT=5s\n" +
+		"TRELLO_API_BASE_URL=" + fixture.fake.URL() + "\n" +
+		"TRELLO_KEY=some key\n" +
+		"TRELLO_NEXT_ACTIONS_LIST_ID=" + fixture.nextActionsListID + "\n" +
+		"TRELLO_TOKEN=some token\n"
+	if string(contents) != expectedContents {
+		t.Errorf("Expected .env file\n%s\ngot\n%s", expectedContents, contents)
+	}
+}
+
+func TestSetupWritesConfigFileThatCanBeLoaded(t *testing.T) {
+	fixture := newSetupFixture(t)
+	defer fixture.close()
+
+	for _, fileName := range []string{"config.yaml", "config.toml"} {
+		configFilePath := filepath.Join(fixture.directory, fileName)
+		args := []string{
+			"--next-actions", "GTD/Next Actions",
+			"--projects", "GTD/Projects",
+			"--done", "GTD/Done",
+			"--output", configFilePath,
+		}
+		if err := runSetup(args, &bytes.Buffer{}); err != nil {
+			t.Fatal(err)
+		}
+
+		config.TeardownEnvironment()
+		cfg, err := config.Load(configFilePath)
+		if err != nil {
+			t.Fatalf("Could not load %s: %s", fileName, err)
+		}
+		config.SetupEnvironment("some key", "some token", "", "")
+
+		if cfg.TrelloNextActionsListID != fixture.nextActionsListID ||
+			cfg.TrelloProjectsListID != fixture.projectsListID ||
+			cfg.TrelloDoneListID != fixture.doneListID ||
+			cfg.TrelloKey != "some key" {
+			t.Errorf("Incorrect config loaded from %s: %+v", fileName, cfg)
+		}
+
+		if err := runSetup(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("Expected existing %s not to be overwritten without --force", fileName)
+		}
+	}
+}
+
+func TestSetupRejectsUnknownLists(t *testing.T) {
+	fixture := newSetupFixture(t)
+	defer fixture.close()
+
+	outputPath := filepath.Join(fixture.directory, ".env")
+	args := []string{"--next-actions", "GTD/Next", "--projects", "Other/Projects", "--output", outputPath}
+	err := runSetup(args, &bytes.Buffer{})
+
+	expectedError := `list reference "GTD/Next" does not match any list on the board; ` +
+		`list reference "Other/Projects" does not match any board`
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %v", expectedError, err)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Error("Expected no .env file to be written")
+	}
+}
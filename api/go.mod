@@ -1,5 +1,9 @@
 module github.com/stevecshanks/next-actions-in-go/api
 
-go 1.13
+go 1.14
 
-require github.com/jarcoal/httpmock v1.0.4
+require (
+	github.com/BurntSushi/toml v0.3.1
+	golang.org/x/sync v0.1.0
+	gopkg.in/yaml.v2 v2.3.0
+)
@@ -1,53 +1,370 @@
-// Package config provides an abstraction around configuration and allows creation from the environment
+// Package config provides an abstraction around configuration and allows creation from the environment and an
+// optional config file
 package config
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// DefaultRequestTimeout is the default overall deadline for handling a single API request
+const DefaultRequestTimeout = 10 * time.Second
+
+// DefaultTrelloMaxConcurrentRequests is the default limit on the number of requests made to Trello at once
+const DefaultTrelloMaxConcurrentRequests = 10
+
+// DefaultTrelloListRefreshInterval is the default time between looking up lists that are configured by name again
+const DefaultTrelloListRefreshInterval = 15 * time.Minute
+
+// DefaultTrelloTodoListNames returns the default names of the list on each project board that holds its actions
+func DefaultTrelloTodoListNames() []string {
+	return []string{"Todo"}
+}
+
+// DefaultTrelloActionsPerProject is the default number of actions returned from the Todo list of each project
+const DefaultTrelloActionsPerProject = 1
+
+// DefaultTrelloBlockedLabelNames returns the default names of labels that mark a card as blocked
+func DefaultTrelloBlockedLabelNames() []string {
+	return []string{"Waiting"}
+}
+
+// StartupCheckWarn makes the API print a report if its configuration doesn't work with Trello, but start anyway
+const StartupCheckWarn = "warn"
+
+// StartupCheckFail makes the API refuse to start if its configuration doesn't work with Trello
+const StartupCheckFail = "fail"
+
+// StartupCheckOff makes the API start without checking its configuration against Trello
+const StartupCheckOff = "off"
+
 // Config represents a configuration for the app
 type Config struct {
-	TrelloKey               string
-	TrelloToken             string
+	TrelloKey   string
+	TrelloToken string
+	// Each list can be given either as an ID or as a "board name/list name" reference, see IsListReference
 	TrelloNextActionsListID string
 	TrelloProjectsListID    string
+	TrelloDoneListID        string
+	// TrelloWaitingForListID is optional, and holds cards that are waiting on someone else
+	TrelloWaitingForListID string
+	// TrelloWaitingForNudgeDays is how many days a card can be waiting before the person it's waiting on should be
+	// nudged, or 0 to never suggest a nudge
+	TrelloWaitingForNudgeDays int
+	// TrelloInboxListID and TrelloSomedayListID are optional, and hold cards that haven't been processed yet and cards
+	// that might be done one day respectively
+	TrelloInboxListID   string
+	TrelloSomedayListID string
+	// TrelloTodoListNames are the names that a project board's Todo list may have, matched ignoring case, with
+	// earlier names preferred if a board has more than one of them
+	TrelloTodoListNames     []string
+	TrelloActionsPerProject int
+	// TrelloSkipBlockedActions makes blocked cards on a project's Todo list be skipped, so that the first cards that
+	// can actually be worked on are returned instead
+	TrelloSkipBlockedActions    bool
+	TrelloBlockedLabelNames     []string
+	TrelloListRefreshInterval   time.Duration
+	TrelloMaxConcurrentRequests int
+	// TrelloAPIBaseURL is empty unless the API should talk to something other than the real Trello API
+	TrelloAPIBaseURL string
+	RequestTimeout   time.Duration
+	// StartupCheck is one of StartupCheckWarn, StartupCheckFail or StartupCheckOff
+	StartupCheck string
+}
+
+// settingNames returns the name of every setting, as used for environment variables. Config files use the same names
+// in lower case.
+func settingNames() []string {
+	return []string{
+		"TRELLO_KEY",
+		"TRELLO_KEY_FILE",
+		"TRELLO_TOKEN",
+		"TRELLO_TOKEN_FILE",
+		"TRELLO_NEXT_ACTIONS_LIST_ID",
+		"TRELLO_PROJECTS_LIST_ID",
+		"TRELLO_DONE_LIST_ID",
+		"TRELLO_WAITING_FOR_LIST_ID",
+		"TRELLO_WAITING_FOR_NUDGE_DAYS",
+		"TRELLO_INBOX_LIST_ID",
+		"TRELLO_SOMEDAY_LIST_ID",
+		"TRELLO_LIST_REFRESH_INTERVAL",
+		"TRELLO_TODO_LIST_NAMES",
+		"TRELLO_ACTIONS_PER_PROJECT",
+		"TRELLO_SKIP_BLOCKED_ACTIONS",
+		"TRELLO_BLOCKED_LABEL_NAMES",
+		"TRELLO_MAX_CONCURRENT_REQUESTS",
+		"TRELLO_API_BASE_URL",
+		"REQUEST_TIMEOUT",
+		"STARTUP_CHECK",
+	}
+}
+
+// secretSettingNames returns the names of settings that can instead be read from a file, by setting the name with a
+// _FILE suffix to its path. This keeps secrets out of the environment, e.g. when using Docker or Kubernetes secrets.
+func secretSettingNames() []string {
+	return []string{"TRELLO_KEY", "TRELLO_TOKEN"}
+}
+
+// credentialSecretNames returns the names of every setting that can hold a secret, including those naming a file
+func credentialSecretNames() []string {
+	names := make([]string, 0)
+	for _, name := range secretSettingNames() {
+		names = append(names, name, name+"_FILE")
+	}
+	return names
+}
+
+// ValidationError lists every problem found with a configuration, so that they can all be fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
 }
 
 // FromEnvironment creates a Config from environment variables
 func FromEnvironment() (*Config, error) {
-	trelloKey, err := requiredEnvironmentVariable("TRELLO_KEY")
-	if err != nil {
-		return nil, err
+	return Load("")
+}
+
+// Load creates a Config from the YAML or TOML file at the specified path, if there is one, with any settings in
+// environment variables taking precedence over those in the file
+func Load(configFilePath string) (*Config, error) {
+	values, fileProblems := loadValues(configFilePath)
+	return fromValues(values, fileProblems)
+}
+
+// Credentials are the settings needed to talk to Trello, which are all that is required before the lists have been
+// chosen
+type Credentials struct {
+	TrelloKey        string
+	TrelloToken      string
+	TrelloAPIBaseURL string
+	// Settings holds the values of the settings the credentials were read from, e.g. TRELLO_KEY_FILE rather than
+	// TRELLO_KEY if the key was read from a file, so that they can be written to a new configuration
+	Settings map[string]string
+}
+
+// LoadCredentials reads the Trello credentials in the same way as Load, without requiring any of the other settings
+func LoadCredentials(configFilePath string) (*Credentials, error) {
+	values, fileProblems := loadValues(configFilePath)
+	s := settings{values: values, problems: fileProblems}
+
+	credentials := &Credentials{
+		TrelloKey:        s.requiredSecret("TRELLO_KEY"),
+		TrelloToken:      s.requiredSecret("TRELLO_TOKEN"),
+		TrelloAPIBaseURL: s.optionalURL("TRELLO_API_BASE_URL"),
+		Settings:         make(map[string]string),
+	}
+	if len(s.problems) > 0 {
+		return nil, &ValidationError{Problems: s.problems}
 	}
 
-	trelloToken, err := requiredEnvironmentVariable("TRELLO_TOKEN")
-	if err != nil {
-		return nil, err
+	for _, name := range append(credentialSecretNames(), "TRELLO_API_BASE_URL") {
+		if value := values[name]; value != "" {
+			credentials.Settings[name] = value
+		}
+	}
+	return credentials, nil
+}
+
+// loadValues reads the value of every setting from the config file, if there is one, and the environment. Problems
+// with the config file are returned so that they can be reported along with any problems with the settings.
+func loadValues(configFilePath string) (map[string]string, []string) {
+	values := make(map[string]string)
+	problems := make([]string, 0)
+	if configFilePath != "" {
+		values, problems = readConfigFile(configFilePath)
+	}
+	// A secret set in the environment replaces the one in the config file, however each of them was given
+	for _, name := range secretSettingNames() {
+		if os.Getenv(name) != "" || os.Getenv(name+"_FILE") != "" {
+			delete(values, name)
+			delete(values, name+"_FILE")
+		}
+	}
+	for _, name := range settingNames() {
+		if value := os.Getenv(name); value != "" {
+			values[name] = value
+		}
+	}
+	return values, problems
+}
+
+func fromValues(values map[string]string, fileProblems []string) (*Config, error) {
+	s := settings{values: values, problems: fileProblems}
+
+	maxConcurrentRequests := s.optionalPositiveInt("TRELLO_MAX_CONCURRENT_REQUESTS", DefaultTrelloMaxConcurrentRequests)
+	actionsPerProject := s.optionalPositiveInt("TRELLO_ACTIONS_PER_PROJECT", DefaultTrelloActionsPerProject)
+
+	cfg := &Config{
+		TrelloKey:                   s.requiredSecret("TRELLO_KEY"),
+		TrelloToken:                 s.requiredSecret("TRELLO_TOKEN"),
+		TrelloNextActionsListID:     s.required("TRELLO_NEXT_ACTIONS_LIST_ID"),
+		TrelloProjectsListID:        s.required("TRELLO_PROJECTS_LIST_ID"),
+		TrelloDoneListID:            s.optional("TRELLO_DONE_LIST_ID"),
+		TrelloWaitingForListID:      s.optional("TRELLO_WAITING_FOR_LIST_ID"),
+		TrelloWaitingForNudgeDays:   s.optionalPositiveInt("TRELLO_WAITING_FOR_NUDGE_DAYS", 0),
+		TrelloInboxListID:           s.optional("TRELLO_INBOX_LIST_ID"),
+		TrelloSomedayListID:         s.optional("TRELLO_SOMEDAY_LIST_ID"),
+		TrelloTodoListNames:         s.optionalList("TRELLO_TODO_LIST_NAMES", DefaultTrelloTodoListNames()),
+		TrelloActionsPerProject:     actionsPerProject,
+		TrelloSkipBlockedActions:    s.optionalBool("TRELLO_SKIP_BLOCKED_ACTIONS"),
+		TrelloBlockedLabelNames:     s.optionalList("TRELLO_BLOCKED_LABEL_NAMES", DefaultTrelloBlockedLabelNames()),
+		TrelloListRefreshInterval:   s.optionalDuration("TRELLO_LIST_REFRESH_INTERVAL", DefaultTrelloListRefreshInterval),
+		TrelloMaxConcurrentRequests: maxConcurrentRequests,
+		TrelloAPIBaseURL:            s.optionalURL("TRELLO_API_BASE_URL"),
+		RequestTimeout:              s.optionalDuration("REQUEST_TIMEOUT", DefaultRequestTimeout),
+		StartupCheck:                s.optionalChoice("STARTUP_CHECK", StartupCheckWarn, StartupCheckFail, StartupCheckOff),
 	}
 
-	trelloNextActionsListID, err := requiredEnvironmentVariable("TRELLO_NEXT_ACTIONS_LIST_ID")
+	if len(s.problems) > 0 {
+		return nil, &ValidationError{Problems: s.problems}
+	}
+	return cfg, nil
+}
+
+// IsListReference returns true if a list setting refers to the list by its board and list names, e.g.
+// "GTD/Next Actions", rather than by ID. Trello IDs never contain a slash.
+func IsListReference(value string) bool {
+	return strings.Contains(value, "/")
+}
+
+// settings reads values for settings, recording any problems with them rather than stopping at the first one
+type settings struct {
+	values   map[string]string
+	problems []string
+}
+
+func (s *settings) problem(format string, args ...interface{}) {
+	s.problems = append(s.problems, fmt.Sprintf(format, args...))
+}
+
+func (s *settings) required(name string) string {
+	value := s.values[name]
+	if value == "" {
+		s.problem("%s is required", name)
+	}
+	return value
+}
+
+// requiredSecret reads a setting either directly or from the file named by the setting with a _FILE suffix, ignoring
+// any trailing newlines in the file
+func (s *settings) requiredSecret(name string) string {
+	fileName := name + "_FILE"
+	value, filePath := s.values[name], s.values[fileName]
+
+	switch {
+	case value != "" && filePath != "":
+		s.problem("only one of %s and %s may be set", name, fileName)
+		return ""
+	case value != "":
+		return value
+	case filePath == "":
+		s.problem("%s or %s is required", name, fileName)
+		return ""
+	}
+
+	contents, err := ioutil.ReadFile(filePath) // nolint:gosec // the secret file is chosen by whoever runs the API
 	if err != nil {
-		return nil, err
+		s.problem("%s could not be read: %s", fileName, err)
+		return ""
+	}
+	value = strings.TrimRight(string(contents), "\r\n")
+	if value == "" {
+		s.problem("%s must not be empty", filePath)
+	}
+	return value
+}
+
+func (s *settings) optional(name string) string {
+	return s.values[name]
+}
+
+func (s *settings) optionalDuration(name string, defaultValue time.Duration) time.Duration {
+	value := s.values[name]
+	if value == "" {
+		return defaultValue
 	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		s.problem("%s must be a positive duration such as 10s, got %s", name, value)
+		return 0
+	}
+	return duration
+}
 
-	trelloProjectsListID, err := requiredEnvironmentVariable("TRELLO_PROJECTS_LIST_ID")
+func (s *settings) optionalPositiveInt(name string, defaultValue int) int {
+	value := s.values[name]
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		s.problem("%s must be a positive whole number, got %s", name, value)
+		return 0
+	}
+	return number
+}
+
+// optionalBool reads a setting that is either true or false, defaulting to false
+func (s *settings) optionalBool(name string) bool {
+	value := s.values[name]
+	if value == "" {
+		return false
+	}
+	boolean, err := strconv.ParseBool(value)
 	if err != nil {
-		return nil, err
+		s.problem("%s must be true or false, got %s", name, value)
+		return false
+	}
+	return boolean
+}
+
+// optionalChoice reads a setting that must be one of the choices, the first of which is the default
+func (s *settings) optionalChoice(name string, choices ...string) string {
+	value := strings.ToLower(s.values[name])
+	if value == "" {
+		return choices[0]
 	}
+	for _, choice := range choices {
+		if value == choice {
+			return value
+		}
+	}
+	s.problem("%s must be one of %s, got %s", name, strings.Join(choices, ", "), s.values[name])
+	return ""
+}
 
-	return &Config{
-		TrelloKey:               trelloKey,
-		TrelloToken:             trelloToken,
-		TrelloNextActionsListID: trelloNextActionsListID,
-		TrelloProjectsListID:    trelloProjectsListID,
-	}, nil
+// optionalList reads a comma separated list of values, ignoring any spaces around them
+func (s *settings) optionalList(name string, defaultValues []string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(s.values[name], ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValues
+	}
+	return values
 }
 
-func requiredEnvironmentVariable(name string) (string, error) {
-	value := os.Getenv(name)
+func (s *settings) optionalURL(name string) string {
+	value := s.values[name]
 	if value == "" {
-		return "", fmt.Errorf("%s is a required environment variable", name)
+		return ""
+	}
+	parsedURL, err := url.Parse(value)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		s.problem("%s must be an absolute http or https URL, got %s", name, value)
+		return ""
 	}
-	return value, nil
+	return strings.TrimSuffix(value, "/")
 }
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 )
 
 func TestFromEnvironmentReturnsValidConfig(t *testing.T) {
@@ -63,3 +67,595 @@ func TestFromEnvironmentRequiresTrelloProjectsListID(t *testing.T) {
 		t.Errorf("FromEnvironment did not fail with missing TRELLO_PROJECTS_LIST_ID: %s", err)
 	}
 }
+
+func TestFromEnvironmentReadsOptionalTrelloDoneListID(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_DONE_LIST_ID", "done list id")
+	defer os.Setenv("TRELLO_DONE_LIST_ID", "")
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.TrelloDoneListID != "done list id" {
+		t.Errorf("Expected TrelloDoneListID %s, got %s", "done list id", config.TrelloDoneListID)
+	}
+}
+
+func TestFromEnvironmentDefaultsRequestTimeout(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.RequestTimeout != DefaultRequestTimeout {
+		t.Errorf("Expected RequestTimeout %s, got %s", DefaultRequestTimeout, config.RequestTimeout)
+	}
+}
+
+func TestFromEnvironmentReadsRequestTimeout(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("REQUEST_TIMEOUT", "2m30s")
+	defer os.Setenv("REQUEST_TIMEOUT", "")
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.RequestTimeout != 150*time.Second {
+		t.Errorf("Expected RequestTimeout %s, got %s", 150*time.Second, config.RequestTimeout)
+	}
+}
+
+func TestFromEnvironmentRejectsInvalidRequestTimeout(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("REQUEST_TIMEOUT", "soon")
+	defer os.Setenv("REQUEST_TIMEOUT", "")
+
+	_, err := FromEnvironment()
+	if err == nil {
+		t.Errorf("FromEnvironment did not fail with invalid REQUEST_TIMEOUT: %s", err)
+	}
+}
+
+func TestFromEnvironmentReadsTrelloMaxConcurrentRequests(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_MAX_CONCURRENT_REQUESTS", "3")
+	defer os.Setenv("TRELLO_MAX_CONCURRENT_REQUESTS", "")
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.TrelloMaxConcurrentRequests != 3 {
+		t.Errorf("Expected TrelloMaxConcurrentRequests %d, got %d", 3, config.TrelloMaxConcurrentRequests)
+	}
+}
+
+func TestFromEnvironmentRejectsInvalidTrelloMaxConcurrentRequests(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_MAX_CONCURRENT_REQUESTS", "0")
+	defer os.Setenv("TRELLO_MAX_CONCURRENT_REQUESTS", "")
+
+	_, err := FromEnvironment()
+	if err == nil {
+		t.Errorf("FromEnvironment did not fail with invalid TRELLO_MAX_CONCURRENT_REQUESTS: %s", err)
+	}
+}
+
+func TestFromEnvironmentReadsTrelloAPIBaseURL(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_API_BASE_URL", "http://localhost:9000/1/")
+	defer os.Setenv("TRELLO_API_BASE_URL", "")
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.TrelloAPIBaseURL != "http://localhost:9000/1" {
+		t.Errorf("Expected TrelloAPIBaseURL %s, got %s", "http://localhost:9000/1", config.TrelloAPIBaseURL)
+	}
+}
+
+func TestFromEnvironmentRejectsInvalidTrelloAPIBaseURL(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_API_BASE_URL", "localhost:9000")
+	defer os.Setenv("TRELLO_API_BASE_URL", "")
+
+	_, err := FromEnvironment()
+	if err == nil {
+		t.Errorf("FromEnvironment did not fail with invalid TRELLO_API_BASE_URL: %s", err)
+	}
+}
+
+func TestFromEnvironmentReportsEveryProblem(t *testing.T) {
+	SetupEnvironment("", "a token", "", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("REQUEST_TIMEOUT", "soon")
+	defer os.Setenv("REQUEST_TIMEOUT", "")
+
+	_, err := FromEnvironment()
+
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("Expected a validation error, got %s", err)
+	}
+	expectedProblems := []string{
+		"TRELLO_KEY or TRELLO_KEY_FILE is required",
+		"TRELLO_NEXT_ACTIONS_LIST_ID is required",
+		"REQUEST_TIMEOUT must be a positive duration such as 10s, got soon",
+	}
+	assertProblemsMatchExpected(t, validationError.Problems, expectedProblems)
+}
+
+func TestLoadReadsYAMLFile(t *testing.T) {
+	configFilePath := writeConfigFile(t, "config.yaml", `
+trello_key: file key
+trello_token: file token
+trello_next_actions_list_id: file next actions list id
+trello_projects_list_id: file projects list id
+trello_max_concurrent_requests: 3
+request_timeout: 5s
+`)
+	defer os.Remove(configFilePath)
+
+	config, err := Load(configFilePath)
+	if err != nil {
+		t.Fatalf("Error returned from Load: %s", err)
+	}
+
+	isValidConfig := config.TrelloKey == "file key" &&
+		config.TrelloToken == "file token" &&
+		config.TrelloNextActionsListID == "file next actions list id" &&
+		config.TrelloProjectsListID == "file projects list id" &&
+		config.TrelloMaxConcurrentRequests == 3 &&
+		config.RequestTimeout == 5*time.Second
+
+	if !isValidConfig {
+		t.Errorf("Incorrect config returned from Load: %+v", config)
+	}
+}
+
+func TestLoadReadsTOMLFile(t *testing.T) {
+	configFilePath := writeConfigFile(t, "config.toml", `
+trello_key = "file key"
+trello_token = "file token"
+trello_next_actions_list_id = "file next actions list id"
+trello_projects_list_id = "file projects list id"
+trello_max_concurrent_requests = 3
+`)
+	defer os.Remove(configFilePath)
+
+	config, err := Load(configFilePath)
+	if err != nil {
+		t.Fatalf("Error returned from Load: %s", err)
+	}
+	if config.TrelloKey != "file key" || config.TrelloMaxConcurrentRequests != 3 {
+		t.Errorf("Incorrect config returned from Load: %+v", config)
+	}
+}
+
+func TestLoadPrefersEnvironmentToFile(t *testing.T) {
+	configFilePath := writeConfigFile(t, "config.yaml", `
+trello_key: file key
+trello_token: file token
+trello_next_actions_list_id: file next actions list id
+trello_projects_list_id: file projects list id
+`)
+	defer os.Remove(configFilePath)
+
+	os.Setenv("TRELLO_KEY", "environment key")
+	defer os.Setenv("TRELLO_KEY", "")
+
+	config, err := Load(configFilePath)
+	if err != nil {
+		t.Fatalf("Error returned from Load: %s", err)
+	}
+	if config.TrelloKey != "environment key" || config.TrelloToken != "file token" {
+		t.Errorf("Incorrect config returned from Load: %+v", config)
+	}
+}
+
+func TestLoadRejectsUnknownSettingsInFile(t *testing.T) {
+	configFilePath := writeConfigFile(t, "config.yaml", "trello_kee: file key\n")
+	defer os.Remove(configFilePath)
+
+	_, err := Load(configFilePath)
+
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("Expected a validation error, got %s", err)
+	}
+	expectedProblems := []string{
+		fmt.Sprintf("trello_kee is not a known setting in config file %s", configFilePath),
+		"TRELLO_KEY or TRELLO_KEY_FILE is required",
+		"TRELLO_TOKEN or TRELLO_TOKEN_FILE is required",
+		"TRELLO_NEXT_ACTIONS_LIST_ID is required",
+		"TRELLO_PROJECTS_LIST_ID is required",
+	}
+	assertProblemsMatchExpected(t, validationError.Problems, expectedProblems)
+}
+
+func TestLoadRejectsUnsupportedFileFormat(t *testing.T) {
+	configFilePath := writeConfigFile(t, "config.json", "{}")
+	defer os.Remove(configFilePath)
+
+	if _, err := Load(configFilePath); err == nil {
+		t.Error("Load did not fail with unsupported config file format")
+	}
+}
+
+func TestLoadReportsFileProblemsAlongWithInvalidSettings(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "")
+	defer TeardownEnvironment()
+	os.Setenv("REQUEST_TIMEOUT", "soon")
+	defer os.Setenv("REQUEST_TIMEOUT", "")
+
+	_, err := Load("/does/not/exist.yaml")
+
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("Expected a validation error, got %s", err)
+	}
+	expectedProblems := []string{
+		"could not read config file: open /does/not/exist.yaml: no such file or directory",
+		"TRELLO_PROJECTS_LIST_ID is required",
+		"REQUEST_TIMEOUT must be a positive duration such as 10s, got soon",
+	}
+	assertProblemsMatchExpected(t, validationError.Problems, expectedProblems)
+}
+
+func TestFromEnvironmentReadsSecretsFromFiles(t *testing.T) {
+	keyFilePath := writeConfigFile(t, "trello_key", "file key\n")
+	defer os.Remove(keyFilePath)
+	tokenFilePath := writeConfigFile(t, "trello_token", "file token\r\n")
+	defer os.Remove(tokenFilePath)
+
+	SetupEnvironment("", "", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_KEY_FILE", keyFilePath)
+	defer os.Setenv("TRELLO_KEY_FILE", "")
+	os.Setenv("TRELLO_TOKEN_FILE", tokenFilePath)
+	defer os.Setenv("TRELLO_TOKEN_FILE", "")
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.TrelloKey != "file key" || config.TrelloToken != "file token" {
+		t.Errorf("Incorrect config returned from FromEnvironment: %+v", config)
+	}
+}
+
+func TestFromEnvironmentRejectsSecretSetTwice(t *testing.T) {
+	keyFilePath := writeConfigFile(t, "trello_key", "file key\n")
+	defer os.Remove(keyFilePath)
+
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_KEY_FILE", keyFilePath)
+	defer os.Setenv("TRELLO_KEY_FILE", "")
+
+	_, err := FromEnvironment()
+
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("Expected a validation error, got %s", err)
+	}
+	expectedProblems := []string{"only one of TRELLO_KEY and TRELLO_KEY_FILE may be set"}
+	assertProblemsMatchExpected(t, validationError.Problems, expectedProblems)
+}
+
+func TestFromEnvironmentRejectsMissingOrEmptySecretFiles(t *testing.T) {
+	tokenFilePath := writeConfigFile(t, "trello_token", "\n")
+	defer os.Remove(tokenFilePath)
+
+	SetupEnvironment("", "", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_KEY_FILE", "/missing/trello_key")
+	defer os.Setenv("TRELLO_KEY_FILE", "")
+	os.Setenv("TRELLO_TOKEN_FILE", tokenFilePath)
+	defer os.Setenv("TRELLO_TOKEN_FILE", "")
+
+	_, err := FromEnvironment()
+
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("Expected a validation error, got %s", err)
+	}
+	if len(validationError.Problems) != 2 {
+		t.Errorf("Expected problems with both secret files, got %q", validationError.Problems)
+	}
+}
+
+func TestLoadPrefersSecretFileInEnvironmentToSecretInFile(t *testing.T) {
+	keyFilePath := writeConfigFile(t, "trello_key", "secret file key\n")
+	defer os.Remove(keyFilePath)
+	configFilePath := writeConfigFile(t, "config.yaml", `
+trello_key: file key
+trello_token: file token
+trello_next_actions_list_id: file next actions list id
+trello_projects_list_id: file projects list id
+`)
+	defer os.Remove(configFilePath)
+
+	os.Setenv("TRELLO_KEY_FILE", keyFilePath)
+	defer os.Setenv("TRELLO_KEY_FILE", "")
+
+	config, err := Load(configFilePath)
+	if err != nil {
+		t.Fatalf("Error returned from Load: %s", err)
+	}
+	if config.TrelloKey != "secret file key" {
+		t.Errorf("Expected TrelloKey %s, got %s", "secret file key", config.TrelloKey)
+	}
+}
+
+func writeConfigFile(t *testing.T, name, contents string) string {
+	file, err := ioutil.TempFile("", "*-"+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(contents); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
+
+func assertProblemsMatchExpected(t *testing.T, problems, expectedProblems []string) {
+	if len(expectedProblems) != len(problems) {
+		t.Fatalf("Expected problems %q, got %q", expectedProblems, problems)
+	}
+	for i := range problems {
+		if problems[i] != expectedProblems[i] {
+			t.Errorf("Expected problem %d to be %q but got %q", i, expectedProblems[i], problems[i])
+		}
+	}
+}
+
+func TestFromEnvironmentDefaultsStartupCheckToWarn(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.StartupCheck != StartupCheckWarn {
+		t.Errorf("Expected StartupCheck %s, got %s", StartupCheckWarn, config.StartupCheck)
+	}
+}
+
+func TestFromEnvironmentRejectsInvalidStartupCheck(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("STARTUP_CHECK", "sometimes")
+	defer os.Setenv("STARTUP_CHECK", "")
+
+	_, err := FromEnvironment()
+	if err == nil {
+		t.Errorf("FromEnvironment did not fail with invalid STARTUP_CHECK: %s", err)
+	}
+}
+
+func TestLoadCredentialsOnlyRequiresKeyAndToken(t *testing.T) {
+	keyFilePath := writeConfigFile(t, "trello_key", "file key\n")
+	defer os.Remove(keyFilePath)
+
+	SetupEnvironment("", "a token", "", "")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_KEY_FILE", keyFilePath)
+	defer os.Setenv("TRELLO_KEY_FILE", "")
+
+	credentials, err := LoadCredentials("")
+	if err != nil {
+		t.Fatalf("Error returned from LoadCredentials: %s", err)
+	}
+	if credentials.TrelloKey != "file key" || credentials.TrelloToken != "a token" {
+		t.Errorf("Incorrect credentials returned from LoadCredentials: %+v", credentials)
+	}
+	expectedSettings := map[string]string{"TRELLO_KEY_FILE": keyFilePath, "TRELLO_TOKEN": "a token"}
+	if fmt.Sprint(credentials.Settings) != fmt.Sprint(expectedSettings) {
+		t.Errorf("Expected settings %v, got %v", expectedSettings, credentials.Settings)
+	}
+}
+
+func TestLoadCredentialsRequiresKeyAndToken(t *testing.T) {
+	SetupEnvironment("", "", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+
+	_, err := LoadCredentials("")
+
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("Expected a validation error, got %s", err)
+	}
+	expectedProblems := []string{
+		"TRELLO_KEY or TRELLO_KEY_FILE is required",
+		"TRELLO_TOKEN or TRELLO_TOKEN_FILE is required",
+	}
+	assertProblemsMatchExpected(t, validationError.Problems, expectedProblems)
+}
+
+func TestFromEnvironmentReadsTrelloListRefreshInterval(t *testing.T) {
+	SetupEnvironment("a key", "a token", "GTD/Next Actions", "GTD/Projects")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_LIST_REFRESH_INTERVAL", "1h")
+	defer os.Setenv("TRELLO_LIST_REFRESH_INTERVAL", "")
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.TrelloListRefreshInterval != time.Hour {
+		t.Errorf("Expected TrelloListRefreshInterval %s, got %s", time.Hour, config.TrelloListRefreshInterval)
+	}
+}
+
+func TestIsListReference(t *testing.T) {
+	if IsListReference("5d8d3e4b8e1c4e2b9c7a6f01") {
+		t.Error("Expected a list ID not to be a list reference")
+	}
+	if !IsListReference("GTD / Next Actions") {
+		t.Error("Expected a board and list name to be a list reference")
+	}
+}
+
+func TestFromEnvironmentReadsTrelloTodoListNames(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if fmt.Sprint(config.TrelloTodoListNames) != "[Todo]" {
+		t.Errorf("Expected default TrelloTodoListNames [Todo], got %q", config.TrelloTodoListNames)
+	}
+
+	os.Setenv("TRELLO_TODO_LIST_NAMES", " Todo, To Do ,,Next ")
+	defer os.Setenv("TRELLO_TODO_LIST_NAMES", "")
+
+	config, err = FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if fmt.Sprintf("%q", config.TrelloTodoListNames) != `["Todo" "To Do" "Next"]` {
+		t.Errorf("Expected TrelloTodoListNames [Todo To Do Next], got %q", config.TrelloTodoListNames)
+	}
+}
+
+func TestLoadReadsListOfTodoListNamesFromFile(t *testing.T) {
+	configFilePath := writeConfigFile(t, "config.toml", `
+trello_key = "file key"
+trello_token = "file token"
+trello_next_actions_list_id = "file next actions list id"
+trello_projects_list_id = "file projects list id"
+trello_todo_list_names = ["Todo", "To Do"]
+`)
+	defer os.Remove(configFilePath)
+
+	config, err := Load(configFilePath)
+	if err != nil {
+		t.Fatalf("Error returned from Load: %s", err)
+	}
+	if fmt.Sprintf("%q", config.TrelloTodoListNames) != `["Todo" "To Do"]` {
+		t.Errorf("Expected TrelloTodoListNames [Todo To Do], got %q", config.TrelloTodoListNames)
+	}
+}
+
+func TestFromEnvironmentReadsProjectActionSettings(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.TrelloActionsPerProject != 1 || config.TrelloSkipBlockedActions {
+		t.Errorf("Expected one action per project without skipping blocked actions, got %+v", config)
+	}
+
+	os.Setenv("TRELLO_ACTIONS_PER_PROJECT", "3")
+	defer os.Setenv("TRELLO_ACTIONS_PER_PROJECT", "")
+	os.Setenv("TRELLO_SKIP_BLOCKED_ACTIONS", "true")
+	defer os.Setenv("TRELLO_SKIP_BLOCKED_ACTIONS", "")
+	os.Setenv("TRELLO_BLOCKED_LABEL_NAMES", "Waiting,On Hold")
+	defer os.Setenv("TRELLO_BLOCKED_LABEL_NAMES", "")
+
+	config, err = FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.TrelloActionsPerProject != 3 || !config.TrelloSkipBlockedActions {
+		t.Errorf("Expected three actions per project skipping blocked actions, got %+v", config)
+	}
+	if fmt.Sprintf("%q", config.TrelloBlockedLabelNames) != `["Waiting" "On Hold"]` {
+		t.Errorf("Expected TrelloBlockedLabelNames [Waiting On Hold], got %q", config.TrelloBlockedLabelNames)
+	}
+}
+
+func TestFromEnvironmentRejectsInvalidProjectActionSettings(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_ACTIONS_PER_PROJECT", "0")
+	defer os.Setenv("TRELLO_ACTIONS_PER_PROJECT", "")
+	os.Setenv("TRELLO_SKIP_BLOCKED_ACTIONS", "sometimes")
+	defer os.Setenv("TRELLO_SKIP_BLOCKED_ACTIONS", "")
+
+	_, err := FromEnvironment()
+
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("Expected a validation error, got %s", err)
+	}
+	expectedProblems := []string{
+		"TRELLO_ACTIONS_PER_PROJECT must be a positive whole number, got 0",
+		"TRELLO_SKIP_BLOCKED_ACTIONS must be true or false, got sometimes",
+	}
+	assertProblemsMatchExpected(t, validationError.Problems, expectedProblems)
+}
+
+func TestFromEnvironmentReadsWaitingForSettings(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.TrelloWaitingForListID != "" || config.TrelloWaitingForNudgeDays != 0 {
+		t.Errorf("Expected no Waiting For list and no nudges, got %+v", config)
+	}
+
+	os.Setenv("TRELLO_WAITING_FOR_LIST_ID", "waiting for list id")
+	defer os.Setenv("TRELLO_WAITING_FOR_LIST_ID", "")
+	os.Setenv("TRELLO_WAITING_FOR_NUDGE_DAYS", "7")
+	defer os.Setenv("TRELLO_WAITING_FOR_NUDGE_DAYS", "")
+
+	config, err = FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.TrelloWaitingForListID != "waiting for list id" || config.TrelloWaitingForNudgeDays != 7 {
+		t.Errorf("Expected Waiting For list with nudges after 7 days, got %+v", config)
+	}
+
+	os.Setenv("TRELLO_WAITING_FOR_NUDGE_DAYS", "a week")
+
+	_, err = FromEnvironment()
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("Expected a validation error, got %s", err)
+	}
+	expectedProblems := []string{"TRELLO_WAITING_FOR_NUDGE_DAYS must be a positive whole number, got a week"}
+	assertProblemsMatchExpected(t, validationError.Problems, expectedProblems)
+}
+
+func TestFromEnvironmentReadsInboxAndSomedayLists(t *testing.T) {
+	SetupEnvironment("a key", "a token", "next actions list id", "projects list id")
+	defer TeardownEnvironment()
+	os.Setenv("TRELLO_INBOX_LIST_ID", "inbox list id")
+	defer os.Setenv("TRELLO_INBOX_LIST_ID", "")
+	os.Setenv("TRELLO_SOMEDAY_LIST_ID", "someday list id")
+	defer os.Setenv("TRELLO_SOMEDAY_LIST_ID", "")
+
+	config, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("Error returned from FromEnvironment: %s", err)
+	}
+	if config.TrelloInboxListID != "inbox list id" || config.TrelloSomedayListID != "someday list id" {
+		t.Errorf("Expected Inbox and Someday/Maybe lists, got %+v", config)
+	}
+}
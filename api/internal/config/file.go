@@ -0,0 +1,77 @@
+package config // nolint:golint // package comment is in another file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// readConfigFile reads settings from a YAML or TOML file, depending on its extension. Settings are keyed by the
+// names of their environment variables, although the file uses them in lower case, e.g. trello_key. Any problems with
+// the file are returned rather than stopping, so they can be reported along with problems with the settings.
+func readConfigFile(filePath string) (map[string]string, []string) {
+	contents, err := ioutil.ReadFile(filePath) // nolint:gosec // the config file is chosen by whoever runs the API
+	if err != nil {
+		return map[string]string{}, []string{fmt.Sprintf("could not read config file: %s", err)}
+	}
+
+	rawValues := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(contents, &rawValues)
+	case ".toml":
+		err = toml.Unmarshal(contents, &rawValues)
+	default:
+		return map[string]string{}, []string{
+			fmt.Sprintf("config file %s must have a .yaml, .yml or .toml extension", filePath),
+		}
+	}
+	if err != nil {
+		return map[string]string{}, []string{fmt.Sprintf("could not parse config file %s: %s", filePath, err)}
+	}
+
+	return fileValues(filePath, rawValues)
+}
+
+// fileValues converts the values in a config file to strings, so they can be validated in the same way as
+// environment variables. Values that can't be converted are left out and reported as problems.
+func fileValues(filePath string, rawValues map[string]interface{}) (map[string]string, []string) {
+	knownNames := make(map[string]bool)
+	for _, name := range settingNames() {
+		knownNames[name] = true
+	}
+
+	values := make(map[string]string)
+	problems := make([]string, 0)
+	for key, rawValue := range rawValues {
+		name := strings.ToUpper(key)
+		if !knownNames[name] {
+			problems = append(problems, fmt.Sprintf("%s is not a known setting in config file %s", key, filePath))
+			continue
+		}
+
+		switch value := rawValue.(type) {
+		case nil:
+			// An empty value in YAML, which leaves the setting unset
+		case string, int, int64, float64, bool:
+			values[name] = fmt.Sprint(value)
+		case []interface{}:
+			// A list, e.g. of Todo list names, which is read in the same way as a comma separated value
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(items, ",")
+		default:
+			problems = append(problems, fmt.Sprintf("%s must be a single value in config file %s", key, filePath))
+		}
+	}
+
+	sort.Strings(problems)
+	return values, problems
+}
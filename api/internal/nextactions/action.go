@@ -3,9 +3,30 @@ package nextactions // nolint:golint // package comment is in another file
 import (
 	"encoding/json"
 	"net/url"
+	"strings"
 	"time"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
 )
 
+// ActionSourceOwned is the source of actions from cards this user is a member of
+const ActionSourceOwned = "owned"
+
+// ActionSourceNextActionsList is the source of actions from cards on the Next Actions list
+const ActionSourceNextActionsList = "nextActionsList"
+
+// ActionSourceProjectTodo is the source of actions from cards at the top of a project's Todo list
+const ActionSourceProjectTodo = "projectTodo"
+
+// ActionSourceWaitingForList is the source of cards on the Waiting For list
+const ActionSourceWaitingForList = "waitingForList"
+
+// ActionSourceInboxList is the source of cards on the Inbox list
+const ActionSourceInboxList = "inboxList"
+
+// ActionSourceSomedayList is the source of cards on the Someday/Maybe list
+const ActionSourceSomedayList = "somedayList"
+
 // Action represents a "next action" in GTD
 type Action struct {
 	ID          string     `json:"id"`
@@ -14,23 +35,57 @@ type Action struct {
 	URL         url.URL    `json:"-"`
 	ImageURL    *url.URL   `json:"-"`
 	ProjectName string     `json:"projectName"`
+	Position    float64    `json:"-"`
+	// Sources lists every source the action was found in, as a card can be found in more than one
+	Sources []string `json:"sources"`
+	// Labels are used as GTD contexts, e.g. "@home" or "@phone"
+	Labels []Label `json:"labels"`
+}
+
+// Label represents a label on the card an Action was made from
+type Label struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func cardLabels(card *trello.Card) []Label {
+	labels := make([]Label, len(card.Labels))
+	for i, label := range card.Labels {
+		labels[i] = Label{Name: label.Name, Color: label.Color}
+	}
+	return labels
+}
+
+// HasLabel returns true if the action has a label with the given name, ignoring case
+func (a *Action) HasLabel(name string) bool {
+	for _, label := range a.Labels {
+		if strings.EqualFold(strings.TrimSpace(label.Name), strings.TrimSpace(name)) {
+			return true
+		}
+	}
+	return false
 }
 
 type actionAlias Action
 
 // MarshalJSON returns a JSON representation of an Action
 func (a *Action) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.toJSON("actions"))
+}
+
+// toJSON returns the JSON representation of an Action as a resource of the given type
+func (a *Action) toJSON(resourceType string) jsonAction {
 	var imageURL *string = nil
 	if a.ImageURL != nil {
 		var u = a.ImageURL.String()
 		imageURL = &u
 	}
-	return json.Marshal(jsonAction{
+	return jsonAction{
 		actionAlias: actionAlias(*a),
-		Type:        "actions",
+		Type:        resourceType,
 		URL:         a.URL.String(),
 		ImageURL:    imageURL,
-	})
+	}
 }
 
 type jsonAction struct {
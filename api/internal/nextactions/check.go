@@ -0,0 +1,131 @@
+package nextactions // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/config"
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+// Check is the outcome of checking a single part of the configuration against Trello. Err is nil if it passed.
+type Check struct {
+	Description string
+	Err         error
+}
+
+// CheckReport is the outcome of checking that the configuration works with Trello
+type CheckReport struct {
+	Checks []Check
+}
+
+// Passed returns true if every check passed
+func (r *CheckReport) Passed() bool {
+	for _, check := range r.Checks {
+		if check.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the report for people to read, with one line per check
+func (r *CheckReport) String() string {
+	var builder strings.Builder
+	builder.WriteString("Checking configuration against Trello:\n")
+
+	passed := 0
+	for _, check := range r.Checks {
+		if check.Err == nil {
+			passed++
+			fmt.Fprintf(&builder, "  ok    %s\n", check.Description)
+		} else {
+			fmt.Fprintf(&builder, "  FAIL  %s: %s\n", check.Description, check.Err)
+		}
+	}
+	fmt.Fprintf(&builder, "%d of %d checks passed\n", passed, len(r.Checks))
+
+	return builder.String()
+}
+
+func (r *CheckReport) add(description string, err error) {
+	r.Checks = append(r.Checks, Check{Description: description, Err: err})
+}
+
+// Checker verifies that the configured credentials and lists work with Trello, so that mistakes in the configuration
+// are found when the API starts rather than on the first request
+type Checker struct {
+	Client trelloClient
+	Config *config.Config
+}
+
+// Check will check that the key and token are valid, that the Next Actions and Projects lists and any optional lists
+// that are configured can be read, and that every project card links to a board with a Todo list. Checks that need
+// Trello to accept the key and token are skipped if it doesn't.
+func (c *Checker) Check(ctx context.Context) *CheckReport {
+	report := &CheckReport{}
+
+	member, err := c.Client.CurrentMember(ctx)
+	if err != nil {
+		report.add("Trello key and token are valid", err)
+		return report
+	}
+	report.add(fmt.Sprintf("Trello key and token are valid for %s", member.Username), nil)
+
+	_, err = c.Client.CardsOnList(ctx, c.Config.TrelloNextActionsListID)
+	report.add(fmt.Sprintf("Next Actions list %s can be read", c.Config.TrelloNextActionsListID), err)
+
+	optionalLists := []struct{ name, id string }{
+		{"Waiting For", c.Config.TrelloWaitingForListID},
+		{"Inbox", c.Config.TrelloInboxListID},
+		{"Someday/Maybe", c.Config.TrelloSomedayListID},
+	}
+	for _, list := range optionalLists {
+		if list.id != "" {
+			_, err = c.Client.CardsOnList(ctx, list.id)
+			report.add(fmt.Sprintf("%s list %s can be read", list.name, list.id), err)
+		}
+	}
+
+	projectCards, err := c.Client.CardsWithAttachmentsOnList(ctx, c.Config.TrelloProjectsListID)
+	report.add(fmt.Sprintf("Projects list %s can be read", c.Config.TrelloProjectsListID), err)
+	if err == nil {
+		c.checkProjects(ctx, report, projectCards)
+	}
+
+	return report
+}
+
+func (c *Checker) checkProjects(ctx context.Context, report *CheckReport, projectCards []trello.Card) {
+	boardIDsByCardID := make(map[string]string)
+	boardIDErrorsByCardID := make(map[string]error)
+	for i := range projectCards {
+		boardID, err := getProjectBoardID(&projectCards[i])
+		if err != nil {
+			boardIDErrorsByCardID[projectCards[i].ID] = err
+		} else {
+			boardIDsByCardID[projectCards[i].ID] = boardID
+		}
+	}
+
+	listsByBoardID, errorsByBoardID, err := c.Client.ListsOnBoards(ctx, uniqueValues(boardIDsByCardID))
+
+	for i := range projectCards {
+		card := &projectCards[i]
+		description := fmt.Sprintf("Project %q links to a board with a Todo list", card.Name)
+
+		boardID := boardIDsByCardID[card.ID]
+		switch {
+		case boardIDErrorsByCardID[card.ID] != nil:
+			report.add(description, boardIDErrorsByCardID[card.ID])
+		case err != nil:
+			report.add(description, err)
+		case errorsByBoardID[boardID] != nil:
+			report.add(description, errorsByBoardID[boardID])
+		default:
+			_, todoListErr := getTodoList(listsByBoardID[boardID], todoListNames(c.Config, card))
+			report.add(description, todoListErr)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package nextactions
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+func TestCheckPassesWithWorkingConfiguration(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &trello.Card{ID: "projectCardId", Name: "https://trello.com/b/aBoardId"})
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "todoListId", Name: "Todo"})
+
+	checker := Checker{fakeClient, testConfig()}
+	report := checker.Check(context.Background())
+
+	if !report.Passed() {
+		t.Errorf("Expected check to pass, got:\n%s", report)
+	}
+	assertCheckDescriptionsMatchExpected(t, report, []string{
+		"Trello key and token are valid for someone",
+		"Next Actions list nextActionsListId can be read",
+		"Projects list projectsListId can be read",
+		`Project "https://trello.com/b/aBoardId" links to a board with a Todo list`,
+	})
+}
+
+func TestCheckReadsOptionalListsIfConfigured(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.SetCardsOnListError("waitingForListId", errors.New("list not found"))
+
+	cfg := testConfig()
+	cfg.TrelloWaitingForListID = "waitingForListId"
+	cfg.TrelloSomedayListID = "somedayListId"
+	checker := Checker{fakeClient, cfg}
+	report := checker.Check(context.Background())
+
+	if report.Passed() {
+		t.Errorf("Expected check to fail, got:\n%s", report)
+	}
+	assertCheckDescriptionsMatchExpected(t, report, []string{
+		"Trello key and token are valid for someone",
+		"Next Actions list nextActionsListId can be read",
+		"Waiting For list waitingForListId can be read",
+		"Someday/Maybe list somedayListId can be read",
+		"Projects list projectsListId can be read",
+	})
+}
+
+func TestCheckStopsWhenCredentialsAreInvalid(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.SetCurrentMemberError(errors.New("invalid key"))
+
+	checker := Checker{fakeClient, testConfig()}
+	report := checker.Check(context.Background())
+
+	if report.Passed() {
+		t.Errorf("Expected check to fail, got:\n%s", report)
+	}
+	assertCheckDescriptionsMatchExpected(t, report, []string{"Trello key and token are valid"})
+}
+
+func TestCheckReportsEveryBrokenListAndProject(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.SetCardsOnListError("nextActionsListId", errors.New("list not found"))
+	fakeClient.AddCardOnList("projectsListId", &trello.Card{ID: "invalidCardId", Name: "Not a board"})
+	fakeClient.AddCardOnList("projectsListId", &trello.Card{ID: "noTodoCardId", Name: "https://trello.com/b/noTodoId"})
+	fakeClient.AddCardOnList("projectsListId", &trello.Card{ID: "brokenCardId", Name: "https://trello.com/b/brokenId"})
+	fakeClient.AddListOnBoard("noTodoId", &trello.List{ID: "doneListId", Name: "Done"})
+	fakeClient.SetListsOnBoardError("brokenId", errors.New("board not found"))
+
+	checker := Checker{fakeClient, testConfig()}
+	report := checker.Check(context.Background())
+
+	expectedErrors := []string{
+		"",
+		"list not found",
+		"",
+		"could not find a board link in the attachments, description or name of card Not a board",
+		"missing Todo list on board",
+		"board not found",
+	}
+	if len(report.Checks) != len(expectedErrors) {
+		t.Fatalf("Expected %d checks, got:\n%s", len(expectedErrors), report)
+	}
+	for i, check := range report.Checks {
+		if (check.Err == nil && expectedErrors[i] != "") || (check.Err != nil && check.Err.Error() != expectedErrors[i]) {
+			t.Errorf("Expected check %d to have error %q, got %v", i, expectedErrors[i], check.Err)
+		}
+	}
+	if !strings.Contains(report.String(), "2 of 6 checks passed") {
+		t.Errorf("Expected report to summarise checks, got:\n%s", report)
+	}
+}
+
+func assertCheckDescriptionsMatchExpected(t *testing.T, report *CheckReport, expectedDescriptions []string) {
+	if len(expectedDescriptions) != len(report.Checks) {
+		t.Fatalf("Expected %d checks, got:\n%s", len(expectedDescriptions), report)
+	}
+	for i, check := range report.Checks {
+		if check.Description != expectedDescriptions[i] {
+			t.Errorf("Expected check %d to be %q but got %q", i, expectedDescriptions[i], check.Description)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package nextactions // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/config"
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+// doneListName is the name of the list on a project board that completed Todo cards are moved to
+const doneListName = "Done"
+
+// Completer allows Next Actions to be marked as complete in Trello
+type Completer struct {
+	Client trelloClient
+	Config *config.Config
+	// ProjectCards keeps the cards on the Projects list between completions, they are fetched every time if nil
+	ProjectCards *ProjectCardCache
+}
+
+// Complete will mark the Next Action with the specified ID as complete. What "complete" means depends on where the
+// action came from:
+//   - cards on the Next Actions list are moved to the configured Done list, or archived if there isn't one
+//   - cards on a project's Todo list are moved to the project board's Done list, or archived if there isn't one
+//   - any other cards (i.e. cards this user is a member of) have their due date marked as complete
+func (c *Completer) Complete(ctx context.Context, actionID string) error {
+	card, err := c.Client.GetCard(ctx, actionID)
+	if err != nil {
+		return err
+	}
+
+	if card.ListID == c.Config.TrelloNextActionsListID {
+		return c.completeNextActionsListCard(ctx, card)
+	}
+
+	boardLists, err := c.Client.ListsOnBoard(ctx, card.BoardID)
+	if err != nil {
+		return err
+	}
+	names, err := c.todoListNamesForBoard(ctx, card.BoardID)
+	if err != nil {
+		return err
+	}
+	if todoList, err := getTodoList(boardLists, names); err == nil && todoList.ID == card.ListID {
+		return c.completeProjectTodoCard(ctx, card, boardLists)
+	}
+
+	return c.Client.MarkCardDueComplete(ctx, card.ID)
+}
+
+// todoListNamesForBoard returns the names the Todo list on the board may have, taking them from the card of the
+// project on the board in the same way as Fetch does
+func (c *Completer) todoListNamesForBoard(ctx context.Context, boardID string) ([]string, error) {
+	projectCards, err := c.ProjectCards.get(ctx, c.Client, c.Config.TrelloProjectsListID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only project cards that name their Todo list change the names, so the board isn't needed if there are none
+	namedProjectCards := make([]*trello.Card, 0)
+	for i := range projectCards {
+		if _, ok := todoListNameFromDescription(projectCards[i].Description); ok {
+			namedProjectCards = append(namedProjectCards, &projectCards[i])
+		}
+	}
+	if len(namedProjectCards) == 0 {
+		return c.Config.TrelloTodoListNames, nil
+	}
+
+	// Project cards link to boards by short link, so the board is needed to match them with its ID
+	boardsByID, errorsByBoardID, err := c.Client.GetBoards(ctx, []string{boardID})
+	if err != nil {
+		return nil, err
+	}
+	if err := errorsByBoardID[boardID]; err != nil {
+		return nil, err
+	}
+	board := boardsByID[boardID]
+
+	for _, projectCard := range namedProjectCards {
+		projectBoardID, err := getProjectBoardID(projectCard)
+		if err == nil && (projectBoardID == board.ID || projectBoardID == board.ShortLink) {
+			return todoListNames(c.Config, projectCard), nil
+		}
+	}
+	return c.Config.TrelloTodoListNames, nil
+}
+
+// ProjectCardCache keeps the cards on the Projects list for a while, so that completing each action doesn't need to
+// fetch them again. It is safe for concurrent use.
+type ProjectCardCache struct {
+	// TTL is how long the cards are kept for before they are fetched again
+	TTL time.Duration
+
+	mutex     sync.Mutex
+	listID    string
+	cards     []trello.Card
+	expiresAt time.Time
+}
+
+// get returns the cards on the Projects list with the specified ID, fetching them if they aren't cached. A nil cache
+// always fetches them.
+func (p *ProjectCardCache) get(ctx context.Context, client trelloClient, listID string) ([]trello.Card, error) {
+	if p == nil {
+		return client.CardsWithAttachmentsOnList(ctx, listID)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.listID == listID && time.Now().Before(p.expiresAt) {
+		return p.cards, nil
+	}
+	cards, err := client.CardsWithAttachmentsOnList(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+	p.listID, p.cards, p.expiresAt = listID, cards, time.Now().Add(p.TTL)
+	return cards, nil
+}
+
+func (c *Completer) completeNextActionsListCard(ctx context.Context, card *trello.Card) error {
+	if c.Config.TrelloDoneListID == "" {
+		return c.Client.ArchiveCard(ctx, card.ID)
+	}
+	return c.Client.MoveCardToList(ctx, card.ID, c.Config.TrelloDoneListID)
+}
+
+func (c *Completer) completeProjectTodoCard(ctx context.Context, card *trello.Card, lists []trello.List) error {
+	for _, list := range lists {
+		if strings.EqualFold(strings.TrimSpace(list.Name), doneListName) {
+			return c.Client.MoveCardToList(ctx, card.ID, list.ID)
+		}
+	}
+	return c.Client.ArchiveCard(ctx, card.ID)
+}
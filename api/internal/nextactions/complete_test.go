@@ -0,0 +1,215 @@
+package nextactions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+func TestCompletingNextActionsListCardArchivesIt(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCard(&trello.Card{ID: "an id", BoardID: "boardId", ListID: "nextActionsListId"})
+
+	completer := Completer{Client: fakeClient, Config: testConfig()}
+	if err := completer.Complete(context.Background(), "an id"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	assertCardIDsMatchExpected(t, fakeClient.archivedCardIDs, []string{"an id"})
+}
+
+func TestCompletingNextActionsListCardMovesItToDoneListIfConfigured(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCard(&trello.Card{ID: "an id", BoardID: "boardId", ListID: "nextActionsListId"})
+
+	cfg := testConfig()
+	cfg.TrelloDoneListID = "doneListId"
+
+	completer := Completer{Client: fakeClient, Config: cfg}
+	if err := completer.Complete(context.Background(), "an id"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if fakeClient.movedCardListIDs["an id"] != "doneListId" {
+		t.Errorf("Expected card to be moved to %s, got %+v", "doneListId", fakeClient.movedCardListIDs)
+	}
+	assertCardIDsMatchExpected(t, fakeClient.archivedCardIDs, []string{})
+}
+
+func TestCompletingProjectTodoCardMovesItToProjectDoneList(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCard(&trello.Card{ID: "an id", BoardID: "aBoardId", ListID: "todoListId"})
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "todoListId", Name: "Todo"})
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "projectDoneListId", Name: "Done"})
+
+	completer := Completer{Client: fakeClient, Config: testConfig()}
+	if err := completer.Complete(context.Background(), "an id"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if fakeClient.movedCardListIDs["an id"] != "projectDoneListId" {
+		t.Errorf("Expected card to be moved to %s, got %+v", "projectDoneListId", fakeClient.movedCardListIDs)
+	}
+}
+
+func TestCompletingProjectTodoCardIgnoresCaseOfDoneList(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCard(&trello.Card{ID: "an id", BoardID: "aBoardId", ListID: "todoListId"})
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "todoListId", Name: "Todo"})
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "projectDoneListId", Name: "DONE "})
+
+	completer := Completer{Client: fakeClient, Config: testConfig()}
+	if err := completer.Complete(context.Background(), "an id"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if fakeClient.movedCardListIDs["an id"] != "projectDoneListId" {
+		t.Errorf("Expected card to be moved to %s, got %+v", "projectDoneListId", fakeClient.movedCardListIDs)
+	}
+	assertCardIDsMatchExpected(t, fakeClient.archivedCardIDs, []string{})
+}
+
+func TestCompletingProjectTodoCardArchivesItIfNoDoneList(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCard(&trello.Card{ID: "an id", BoardID: "aBoardId", ListID: "todoListId"})
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "todoListId", Name: "Todo"})
+
+	completer := Completer{Client: fakeClient, Config: testConfig()}
+	if err := completer.Complete(context.Background(), "an id"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	assertCardIDsMatchExpected(t, fakeClient.archivedCardIDs, []string{"an id"})
+}
+
+func TestCompletingOwnedCardMarksItDueComplete(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCard(&trello.Card{ID: "an id", BoardID: "aBoardId", ListID: "someListId"})
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "todoListId", Name: "Todo"})
+
+	completer := Completer{Client: fakeClient, Config: testConfig()}
+	if err := completer.Complete(context.Background(), "an id"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	assertCardIDsMatchExpected(t, fakeClient.dueCompleteCardIDs, []string{"an id"})
+	assertCardIDsMatchExpected(t, fakeClient.archivedCardIDs, []string{})
+}
+
+func TestCompletingMissingCardReturnsError(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+
+	completer := Completer{Client: fakeClient, Config: testConfig()}
+	err := completer.Complete(context.Background(), "missing id")
+
+	expectedError := fmt.Errorf("card with id %s not found", "missing id")
+	if err == nil || err.Error() != expectedError.Error() {
+		t.Errorf("Expected error %s, got %s", expectedError, err)
+	}
+}
+
+func assertCardIDsMatchExpected(t *testing.T, cardIDs, expectedCardIDs []string) {
+	if len(expectedCardIDs) != len(cardIDs) {
+		t.Fatalf("Unexpected card IDs, expected %v and got %v", expectedCardIDs, cardIDs)
+	}
+	for i := range cardIDs {
+		if cardIDs[i] != expectedCardIDs[i] {
+			t.Errorf("Unexpected card IDs, expected %v and got %v", expectedCardIDs, cardIDs)
+		}
+	}
+}
+
+func TestCompletingProjectTodoCardAgainstFakeTrello(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	firstTodoCardID := fake.AddCard(trello.FakeCard{ListID: fake.todoListID, Name: "First Todo"})
+	secondTodoCardID := fake.AddCard(trello.FakeCard{ListID: fake.todoListID, Name: "Second Todo"})
+	nextTodoCardID := fake.AddCard(trello.FakeCard{ListID: fake.todoListID, Name: "Third Todo"})
+
+	client := fake.Client()
+	completer := Completer{Client: client, Config: fake.cfg, ProjectCards: &ProjectCardCache{TTL: time.Minute}}
+	for _, todoCardID := range []string{firstTodoCardID, secondTodoCardID} {
+		if err := completer.Complete(context.Background(), todoCardID); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		card, _ := fake.Card(todoCardID)
+		if card.ListID != fake.doneListID {
+			t.Errorf("Expected card to be moved to %s, got %s", fake.doneListID, card.ListID)
+		}
+	}
+	// The project cards are cached between completions
+	projectsRequestCount := 0
+	for _, request := range fake.Requests() {
+		if strings.Contains(request, fake.cfg.TrelloProjectsListID) {
+			projectsRequestCount++
+		}
+	}
+	if projectsRequestCount != 1 {
+		t.Errorf("Expected 1 request for projects, got %q", fake.Requests())
+	}
+
+	fetcher := Fetcher{Client: client, Config: fake.cfg}
+	actions, _, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(actions) != 1 || actions[0].ID != nextTodoCardID {
+		t.Errorf("Expected only action %s after completing the others, got %+v", nextTodoCardID, actions)
+	}
+}
+
+func TestCompletingCardOnTodoListNamedByProjectAgainstFakeTrello(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	boardID := fake.AddBoard(trello.FakeBoard{Name: "Other Project"})
+	fake.AddList(trello.FakeList{BoardID: boardID, Name: "Todo"})
+	nextStepsListID := fake.AddList(trello.FakeList{BoardID: boardID, Name: "Next Steps"})
+	doneListID := fake.AddList(trello.FakeList{BoardID: boardID, Name: "Done"})
+	board, _ := fake.Board(boardID)
+	fake.AddCard(trello.FakeCard{
+		ListID:      fake.cfg.TrelloProjectsListID,
+		Name:        trello.BoardBaseURL + board.ShortLink + "/other-project",
+		Description: "Todo list: Next Steps",
+	})
+	cardID := fake.AddCard(trello.FakeCard{ListID: nextStepsListID, Name: "Next Step"})
+
+	completer := Completer{Client: fake.Client(), Config: fake.cfg}
+	if err := completer.Complete(context.Background(), cardID); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	card, _ := fake.Card(cardID)
+	if card.ListID != doneListID {
+		t.Errorf("Expected card to be moved to %s, got %s", doneListID, card.ListID)
+	}
+}
+
+func TestCompletingCardOnConfiguredTodoListOfProjectThatNamesAnotherMarksItDueComplete(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	boardID := fake.AddBoard(trello.FakeBoard{Name: "Other Project"})
+	todoListID := fake.AddList(trello.FakeList{BoardID: boardID, Name: "Todo"})
+	fake.AddList(trello.FakeList{BoardID: boardID, Name: "Next"})
+	doneListID := fake.AddList(trello.FakeList{BoardID: boardID, Name: "Done"})
+	board, _ := fake.Board(boardID)
+	fake.AddCard(trello.FakeCard{
+		ListID:      fake.cfg.TrelloProjectsListID,
+		Name:        trello.BoardBaseURL + board.ShortLink + "/other-project",
+		Description: "Todo list: Next",
+	})
+	cardID := fake.AddCard(trello.FakeCard{ListID: todoListID, Name: "Not a Todo"})
+
+	completer := Completer{Client: fake.Client(), Config: fake.cfg}
+	if err := completer.Complete(context.Background(), cardID); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	card, _ := fake.Card(cardID)
+	if card.ListID == doneListID || !card.DueComplete {
+		t.Errorf("Expected card to be marked due complete on %s, got %+v", todoListID, card)
+	}
+}
@@ -0,0 +1,111 @@
+package nextactions // nolint:golint // package comment is in another file
+
+import (
+	"strings"
+	"time"
+)
+
+// Filter decides whether an action should be returned. Filters can be combined with All and Any.
+type Filter func(action *Action) bool
+
+// FilterActions returns the actions the filter allows, in the same order
+func FilterActions(actions []Action, filter Filter) []Action {
+	filtered := make([]Action, 0, len(actions))
+	for i := range actions {
+		if filter(&actions[i]) {
+			filtered = append(filtered, actions[i])
+		}
+	}
+	return filtered
+}
+
+// All returns a filter allowing actions that every one of the filters allows, or every action if there are none
+func All(filters ...Filter) Filter {
+	return func(action *Action) bool {
+		for _, filter := range filters {
+			if !filter(action) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any returns a filter allowing actions that at least one of the filters allows, or every action if there are none
+func Any(filters ...Filter) Filter {
+	return func(action *Action) bool {
+		for _, filter := range filters {
+			if filter(action) {
+				return true
+			}
+		}
+		return len(filters) == 0
+	}
+}
+
+// InProject allows actions from the project with the given name, ignoring case
+func InProject(name string) Filter {
+	return func(action *Action) bool {
+		return strings.EqualFold(strings.TrimSpace(action.ProjectName), strings.TrimSpace(name))
+	}
+}
+
+// DueBefore allows actions that are due before the given time
+func DueBefore(t time.Time) Filter {
+	return func(action *Action) bool {
+		return action.DueBy != nil && action.DueBy.Before(t)
+	}
+}
+
+// DueAfter allows actions that are due after the given time
+func DueAfter(t time.Time) Filter {
+	return func(action *Action) bool {
+		return action.DueBy != nil && action.DueBy.After(t)
+	}
+}
+
+// Overdue allows actions whose due date has passed
+func Overdue(now time.Time) Filter {
+	return DueBefore(now)
+}
+
+// HasDueDate allows actions that have a due date if hasDueDate is true, or that don't have one if it is false
+func HasDueDate(hasDueDate bool) Filter {
+	return func(action *Action) bool {
+		return (action.DueBy != nil) == hasDueDate
+	}
+}
+
+// FromSource allows actions found in the given source, e.g. ActionSourceOwned
+func FromSource(source string) Filter {
+	return func(action *Action) bool {
+		for _, actionSource := range action.Sources {
+			if actionSource == source {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithLabel allows actions with a label of the given name, ignoring case
+func WithLabel(name string) Filter {
+	return func(action *Action) bool {
+		return action.HasLabel(name)
+	}
+}
+
+// NameContains allows actions whose name contains the given text, ignoring case
+func NameContains(text string) Filter {
+	text = strings.ToLower(text)
+	return func(action *Action) bool {
+		return strings.Contains(strings.ToLower(action.Name), text)
+	}
+}
+
+// Not returns a filter allowing the actions that the filter doesn't allow
+func Not(filter Filter) Filter {
+	return func(action *Action) bool {
+		return !filter(action)
+	}
+}
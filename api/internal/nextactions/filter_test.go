@@ -0,0 +1,75 @@
+package nextactions
+
+import (
+	"testing"
+	"time"
+)
+
+func filterTestActions(now time.Time) []Action {
+	overdue := now.Add(-time.Hour)
+	dueLater := now.Add(7 * 24 * time.Hour)
+
+	return []Action{
+		{
+			ID:          "overdue",
+			Name:        "Call the bank",
+			DueBy:       &overdue,
+			ProjectName: "Money",
+			Sources:     []string{ActionSourceOwned},
+			Labels:      []Label{{Name: "@phone"}},
+		},
+		{
+			ID:          "later",
+			Name:        "Write report",
+			DueBy:       &dueLater,
+			ProjectName: "Work",
+			Sources:     []string{ActionSourceNextActionsList, ActionSourceProjectTodo},
+			Labels:      []Label{{Name: "@office"}, {Name: "@computer"}},
+		},
+		{
+			ID:      "no due date",
+			Name:    "Buy printer paper",
+			Sources: []string{ActionSourceNextActionsList},
+			Labels:  []Label{{Name: "@errands"}},
+		},
+	}
+}
+
+func TestFilters(t *testing.T) {
+	now := time.Date(2020, 2, 12, 16, 24, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		filter      Filter
+		expectedIDs []string
+	}{
+		{"no filters", All(), []string{"overdue", "later", "no due date"}},
+		{"project", InProject(" work"), []string{"later"}},
+		{"due before", DueBefore(now.Add(24 * time.Hour)), []string{"overdue"}},
+		{"due after", DueAfter(now), []string{"later"}},
+		{"overdue", Overdue(now), []string{"overdue"}},
+		{"not overdue", Not(Overdue(now)), []string{"later", "no due date"}},
+		{"has due date", HasDueDate(true), []string{"overdue", "later"}},
+		{"has no due date", HasDueDate(false), []string{"no due date"}},
+		{"source", FromSource(ActionSourceNextActionsList), []string{"later", "no due date"}},
+		{"label", WithLabel("@Office"), []string{"later"}},
+		{"name", NameContains("PAPER"), []string{"no due date"}},
+		{"all", All(FromSource(ActionSourceNextActionsList), HasDueDate(true)), []string{"later"}},
+		{"any", Any(WithLabel("@phone"), WithLabel("@errands")), []string{"overdue", "no due date"}},
+		{"any of none", Any(), []string{"overdue", "later", "no due date"}},
+	}
+	for _, testCase := range testCases {
+		filtered := FilterActions(filterTestActions(now), testCase.filter)
+
+		if len(filtered) != len(testCase.expectedIDs) {
+			t.Errorf("Expected %s filter to return %q, got %+v", testCase.name, testCase.expectedIDs, filtered)
+			continue
+		}
+		for i := range filtered {
+			if filtered[i].ID != testCase.expectedIDs[i] {
+				t.Errorf("Expected %s filter to return %q, got %+v", testCase.name, testCase.expectedIDs, filtered)
+				break
+			}
+		}
+	}
+}
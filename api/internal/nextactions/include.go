@@ -0,0 +1,75 @@
+package nextactions // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+// IncludeArchived is the name used in ParseInclude for archived cards
+const IncludeArchived = "archived"
+
+// IncludeCompleted is the name used in ParseInclude for cards whose due date has been marked as complete
+const IncludeCompleted = "completed"
+
+// IncludeTemplates is the name used in ParseInclude for template cards
+const IncludeTemplates = "templates"
+
+// Include chooses which kinds of card are returned as actions even though they can't be worked on, which is useful
+// when auditing a board. By default none of them are returned.
+type Include struct {
+	Archived  bool
+	Completed bool
+	Templates bool
+}
+
+// ParseInclude parses a comma separated list of the kinds of card to include, e.g. "archived,templates"
+func ParseInclude(value string) (Include, error) {
+	include := Include{}
+	for _, name := range strings.Split(value, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case IncludeArchived:
+			include.Archived = true
+		case IncludeCompleted:
+			include.Completed = true
+		case IncludeTemplates:
+			include.Templates = true
+		default:
+			return Include{}, fmt.Errorf(
+				"cannot include %q, expected any of %s, %s and %s",
+				name,
+				IncludeArchived,
+				IncludeCompleted,
+				IncludeTemplates,
+			)
+		}
+	}
+	return include, nil
+}
+
+// context returns a context that asks Trello for archived cards too if they are included, since they are left out of
+// card lists otherwise
+func (i Include) context(ctx context.Context) context.Context {
+	if i.Archived {
+		return trello.WithArchivedCards(ctx)
+	}
+	return ctx
+}
+
+// allows returns true if the card should be returned as an action
+func (i Include) allows(card *trello.Card) bool {
+	return (i.Archived || !card.Closed) && (i.Completed || !card.DueComplete) && (i.Templates || !card.IsTemplate)
+}
+
+func (i Include) filter(cards []trello.Card) []trello.Card {
+	allowedCards := make([]trello.Card, 0, len(cards))
+	for j := range cards {
+		if i.allows(&cards[j]) {
+			allowedCards = append(allowedCards, cards[j])
+		}
+	}
+	return allowedCards
+}
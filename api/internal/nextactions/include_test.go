@@ -0,0 +1,142 @@
+package nextactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+func TestParseInclude(t *testing.T) {
+	testCases := map[string]Include{
+		"":                              {},
+		"archived":                      {Archived: true},
+		"completed, templates":          {Completed: true, Templates: true},
+		"archived,completed,templates,": {Archived: true, Completed: true, Templates: true},
+	}
+	for value, expected := range testCases {
+		include, err := ParseInclude(value)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %s", value, err)
+		}
+		if include != expected {
+			t.Errorf("Expected %+v for %q, got %+v", expected, value, include)
+		}
+	}
+}
+
+func TestParseIncludeRejectsUnknownValues(t *testing.T) {
+	_, err := ParseInclude("archived,deleted")
+
+	expectedError := `cannot include "deleted", expected any of archived, completed and templates`
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %v", expectedError, err)
+	}
+}
+
+func hiddenCardsFakeClient() *fakeTrelloClient {
+	projectCard := trello.Card{ID: "project id", Name: "https://trello.com/b/aBoardId"}
+	todoList := trello.List{ID: "todoListId", Name: "Todo"}
+
+	completedCard := trello.Card{ID: "completed id", Name: "completed", DueComplete: true, BoardID: "boardId"}
+	archivedCard := trello.Card{ID: "archived id", Name: "archived", Closed: true, BoardID: "boardId"}
+	templateCard := trello.Card{ID: "template id", Name: "template", IsTemplate: true, BoardID: "boardId"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddOwnedCard(&completedCard)
+	fakeClient.AddCardOnList("nextActionsListId", &archivedCard)
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+	fakeClient.AddListOnBoard("aBoardId", &todoList)
+	fakeClient.AddCardOnList("todoListId", &templateCard)
+	fakeClient.AddCardOnList("todoListId", &trello.Card{ID: "an id", Name: "a name", BoardID: "boardId"})
+	return fakeClient
+}
+
+func TestArchivedCompletedAndTemplateCardsAreNotReturned(t *testing.T) {
+	fetcher := Fetcher{Client: hiddenCardsFakeClient(), Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedActions := []Action{
+		{ID: "an id", Name: "a name", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestArchivedCompletedAndTemplateCardsAreReturnedIfIncluded(t *testing.T) {
+	fetcher := Fetcher{
+		Client:  hiddenCardsFakeClient(),
+		Config:  testConfig(),
+		Include: Include{Archived: true, Completed: true, Templates: true},
+	}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedActions := []Action{
+		{ID: "archived id", Name: "archived", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+		{ID: "completed id", Name: "completed", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+		{ID: "template id", Name: "template", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestArchivedCardsAreFetchedFromFakeTrelloIfIncluded(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	archivedOwnedCardID := fake.AddOwnedCard(trello.FakeCard{ListID: fake.doneListID, Name: "Owned", Closed: true})
+	archivedNextActionID := fake.AddCard(trello.FakeCard{
+		ListID: fake.cfg.TrelloNextActionsListID,
+		Name:   "Next Action",
+		Closed: true,
+	})
+	todoCardID := fake.AddCard(trello.FakeCard{ListID: fake.todoListID, Name: "Todo"})
+
+	fetcher := Fetcher{Client: fake.Client(), Config: fake.cfg}
+	actions, _, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assertCardIDsMatchExpected(t, actionIDs(actions), []string{todoCardID})
+
+	fetcher.Include = Include{Archived: true}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assertCardIDsMatchExpected(t, actionIDs(actions), []string{archivedOwnedCardID, archivedNextActionID, todoCardID})
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestTemplateCardsAreFetchedFromFakeTrelloIfIncluded(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	templateCardID := fake.AddCard(trello.FakeCard{
+		ListID:     fake.cfg.TrelloNextActionsListID,
+		Name:       "Template",
+		IsTemplate: true,
+	})
+	todoCardID := fake.AddCard(trello.FakeCard{ListID: fake.todoListID, Name: "Todo"})
+
+	fetcher := Fetcher{Client: fake.Client(), Config: fake.cfg}
+	actions, _, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assertCardIDsMatchExpected(t, actionIDs(actions), []string{todoCardID})
+
+	fetcher.Include = Include{Templates: true}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assertCardIDsMatchExpected(t, actionIDs(actions), []string{templateCardID, todoCardID})
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
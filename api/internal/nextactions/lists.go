@@ -0,0 +1,65 @@
+package nextactions // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ListNotConfiguredError is returned when fetching from an optional list that hasn't been configured
+type ListNotConfiguredError struct {
+	ListName string
+}
+
+func (e *ListNotConfiguredError) Error() string {
+	return fmt.Sprintf("no %s list is configured", e.ListName)
+}
+
+// FetchInbox will fetch the cards on the Inbox list, which are things that haven't been processed yet. As with Fetch,
+// problems with boards are returned as warnings.
+func (f *Fetcher) FetchInbox(ctx context.Context) ([]Action, []Warning, error) {
+	limitedFetcher, ctx := f.limit(ctx)
+	return limitedFetcher.fetchOptionalList(ctx, "Inbox", f.Config.TrelloInboxListID, ActionSourceInboxList)
+}
+
+// FetchSomeday will fetch the cards on the Someday/Maybe list, which are things that might be done one day but aren't
+// being worked on now. As with Fetch, problems with boards are returned as warnings.
+func (f *Fetcher) FetchSomeday(ctx context.Context) ([]Action, []Warning, error) {
+	limitedFetcher, ctx := f.limit(ctx)
+	return limitedFetcher.fetchOptionalList(ctx, "Someday/Maybe", f.Config.TrelloSomedayListID, ActionSourceSomedayList)
+}
+
+// CountInbox returns the number of cards on the Inbox list, without fetching anything else about them
+func (f *Fetcher) CountInbox(ctx context.Context) (int, error) {
+	if f.Config.TrelloInboxListID == "" {
+		return 0, &ListNotConfiguredError{ListName: "Inbox"}
+	}
+	limitedFetcher, ctx := f.limit(ctx)
+	cards, err := limitedFetcher.fetchListCards(ctx, f.Config.TrelloInboxListID)
+	if err != nil {
+		return 0, err
+	}
+	return len(cards), nil
+}
+
+func (f *Fetcher) fetchOptionalList(
+	ctx context.Context,
+	listName string,
+	listID string,
+	source string,
+) ([]Action, []Warning, error) {
+	if listID == "" {
+		return nil, nil, &ListNotConfiguredError{ListName: listName}
+	}
+
+	cards, err := f.fetchListCards(ctx, listID)
+	if err != nil {
+		return nil, nil, err
+	}
+	actions, warnings, err := f.listCardsToActions(ctx, cards, source)
+	if err != nil {
+		return nil, nil, err
+	}
+	SortActions(actions, f.Sort, time.Now())
+	return actions, warnings, nil
+}
@@ -0,0 +1,118 @@
+package nextactions
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+func inboxAndSomedayFetcher() *Fetcher {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("inboxListId", &trello.Card{ID: "second", Name: "Idea", BoardID: "boardId", Position: 2})
+	fakeClient.AddCardOnList("inboxListId", &trello.Card{ID: "first", Name: "Email", BoardID: "boardId", Position: 1})
+	fakeClient.AddCardOnList("somedayListId", &trello.Card{ID: "someday", Name: "Learn Go", BoardID: "boardId"})
+
+	cfg := testConfig()
+	cfg.TrelloInboxListID = "inboxListId"
+	cfg.TrelloSomedayListID = "somedayListId"
+	return &Fetcher{Client: fakeClient, Config: cfg}
+}
+
+func TestInboxCardsAreReturnedAsActions(t *testing.T) {
+	fetcher := inboxAndSomedayFetcher()
+	actions, warnings, err := fetcher.FetchInbox(context.Background())
+
+	expectedActions := []Action{
+		{ID: "first", Name: "Email", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+		{ID: "second", Name: "Idea", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+	if len(actions[0].Sources) != 1 || actions[0].Sources[0] != ActionSourceInboxList {
+		t.Errorf("Expected source %s, got %q", ActionSourceInboxList, actions[0].Sources)
+	}
+}
+
+func TestSomedayCardsAreReturnedAsActions(t *testing.T) {
+	fetcher := inboxAndSomedayFetcher()
+	actions, warnings, err := fetcher.FetchSomeday(context.Background())
+
+	expectedActions := []Action{
+		{ID: "someday", Name: "Learn Go", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestCountInbox(t *testing.T) {
+	fetcher := inboxAndSomedayFetcher()
+	count, err := fetcher.CountInbox(context.Background())
+
+	if err != nil || count != 2 {
+		t.Errorf("Expected 2 cards in the Inbox, got %d and error %v", count, err)
+	}
+}
+
+func TestOptionalListsMustBeConfigured(t *testing.T) {
+	fetcher := Fetcher{Client: newFakeTrelloClient(), Config: testConfig()}
+
+	_, _, inboxErr := fetcher.FetchInbox(context.Background())
+	_, _, somedayErr := fetcher.FetchSomeday(context.Background())
+	_, countErr := fetcher.CountInbox(context.Background())
+
+	expectedErrors := map[string]error{
+		"no Inbox list is configured":         inboxErr,
+		"no Someday/Maybe list is configured": somedayErr,
+	}
+	for expectedError, err := range expectedErrors {
+		var listNotConfiguredError *ListNotConfiguredError
+		if !errors.As(err, &listNotConfiguredError) || err.Error() != expectedError {
+			t.Errorf("Expected error %q, got %v", expectedError, err)
+		}
+	}
+	if countErr == nil || countErr.Error() != "no Inbox list is configured" {
+		t.Errorf("Expected the Inbox not to be counted, got %v", countErr)
+	}
+}
+
+func TestLimitedFetcherSharesLimitBetweenFetchAndCountInbox(t *testing.T) {
+	fetcher := inboxAndSomedayFetcher()
+	fakeClient := fetcher.Client.(*fakeTrelloClient)
+	fakeClient.requestDelay = 5 * time.Millisecond
+	fakeClient.AddCardOnList("nextActionsListId", &trello.Card{ID: "next", Name: "Next", BoardID: "boardId"})
+	fetcher.Config.TrelloMaxConcurrentRequests = 1
+
+	limitedFetcher := fetcher.Limited()
+	if limitedFetcher.Limited() != limitedFetcher {
+		t.Errorf("Expected a limited fetcher not to be limited again")
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := limitedFetcher.CountInbox(context.Background()); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	}()
+	if _, _, err := limitedFetcher.Fetch(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	wg.Wait()
+
+	if fakeClient.maxRequestsInFlight != 1 {
+		t.Errorf("Expected at most %d request in flight, got %d", 1, fakeClient.maxRequestsInFlight)
+	}
+}
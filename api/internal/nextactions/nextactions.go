@@ -2,204 +2,461 @@
 package nextactions
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"regexp"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/stevecshanks/next-actions-in-go/api/internal/config"
 	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+	"golang.org/x/sync/errgroup"
 )
 
 type trelloClient interface {
-	OwnedCards() ([]trello.Card, error)
-	CardsOnList(listID string) ([]trello.Card, error)
-	ListsOnBoard(boardID string) ([]trello.List, error)
-	GetBoard(boardID string) (*trello.Board, error)
+	CurrentMember(ctx context.Context) (*trello.Member, error)
+	OwnedCards(ctx context.Context) ([]trello.Card, error)
+	CardsOnList(ctx context.Context, listID string) ([]trello.Card, error)
+	CardsWithAttachmentsOnList(ctx context.Context, listID string) ([]trello.Card, error)
+	ListsOnBoard(ctx context.Context, boardID string) ([]trello.List, error)
+	CardsOnLists(ctx context.Context, listIDs []string) (map[string][]trello.Card, map[string]error, error)
+	CardsWithChecklistsOnLists(
+		ctx context.Context,
+		listIDs []string,
+	) (map[string][]trello.Card, map[string]error, error)
+	ListsOnBoards(ctx context.Context, boardIDs []string) (map[string][]trello.List, map[string]error, error)
+	GetBoards(ctx context.Context, boardIDs []string) (map[string]*trello.Board, map[string]error, error)
+	GetCard(ctx context.Context, cardID string) (*trello.Card, error)
+	MarkCardDueComplete(ctx context.Context, cardID string) error
+	ArchiveCard(ctx context.Context, cardID string) error
+	MoveCardToList(ctx context.Context, cardID, listID string) error
 }
 
 // Fetcher allows Next Actions to be fetched from Trello
 type Fetcher struct {
 	Client trelloClient
 	Config *config.Config
+	// Include chooses which of the cards that are hidden by default are returned anyway
+	Include Include
+	// Sort chooses the order actions are returned in, SortSmart if not set
+	Sort Sort
+
+	limiter *trello.RequestLimiter
 }
 
-// Fetch will fetch a list of Next Actions from Trello
-func (f *Fetcher) Fetch() ([]Action, error) {
-	allCards := make([]trello.Card, 0)
+// Fetch will fetch a list of Next Actions from Trello. Problems with individual projects or boards do not prevent
+// other actions from being returned, and are instead returned as warnings. If fetching from any of the top-level
+// lists fails, or the context is done, any outstanding requests are cancelled and an error is returned.
+//
+// The number of concurrent requests made to Trello is limited across the whole fetch by the
+// TrelloMaxConcurrentRequests config setting, to avoid tripping Trello's rate limits.
+func (f *Fetcher) Fetch(ctx context.Context) ([]Action, []Warning, error) {
+	limitedFetcher, ctx := f.limit(ctx)
+	return limitedFetcher.fetch(ctx)
+}
 
-	ownedCards, err := f.fetchOwnedCards()
-	if err != nil {
-		return nil, err
+// Limited returns a copy of the fetcher whose requests are limited by the TrelloMaxConcurrentRequests config setting,
+// or the fetcher itself if it is already limited. Fetch and the other methods limit their own requests anyway, but
+// fetches that run at the same time, such as Fetch and CountInbox, need to share a Limited fetcher for the limit to
+// apply to all of them together.
+func (f *Fetcher) Limited() *Fetcher {
+	if f.limiter != nil {
+		return f
+	}
+	limit := f.Config.TrelloMaxConcurrentRequests
+	if limit <= 0 {
+		limit = config.DefaultTrelloMaxConcurrentRequests
+	}
+	limitedFetcher := *f
+	limitedFetcher.limiter = trello.NewRequestLimiter(limit)
+	return &limitedFetcher
+}
+
+// limit returns the Limited fetcher, along with a context that applies its limit to each request made to Trello
+func (f *Fetcher) limit(ctx context.Context) (*Fetcher, context.Context) {
+	limitedFetcher := f.Limited()
+	return limitedFetcher, trello.WithRequestLimiter(ctx, limitedFetcher.limiter)
+}
+
+func (f *Fetcher) fetch(ctx context.Context) ([]Action, []Warning, error) {
+	var ownedCards, nextActionsCards, projectTodoCards []trello.Card
+	var projectWarnings []Warning
+
+	g, groupCtx := errgroup.WithContext(ctx)
+
+	g.Go(func() (err error) {
+		ownedCards, err = f.fetchOwnedCards(groupCtx)
+		return err
+	})
+	g.Go(func() (err error) {
+		nextActionsCards, err = f.fetchCardsOnNextActionsList(groupCtx)
+		return err
+	})
+	g.Go(func() (err error) {
+		projectTodoCards, projectWarnings, err = f.fetchProjectTodoListCards(groupCtx)
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
 	}
-	allCards = append(allCards, ownedCards...)
 
-	nextActionsCards, err := f.fetchCardsOnNextActionsList()
+	allCards, sourcesByCardID := mergeSources(map[string][]trello.Card{
+		ActionSourceOwned:           ownedCards,
+		ActionSourceNextActionsList: nextActionsCards,
+		ActionSourceProjectTodo:     projectTodoCards,
+	})
+
+	boardsByID, boardWarnings, err := f.fetchAllBoards(ctx, allCards)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	allCards = append(allCards, nextActionsCards...)
 
-	projectTodoCards, err := f.fetchProjectTodoListCards()
+	warnings := make([]Warning, 0, len(projectWarnings)+len(boardWarnings))
+	warnings = append(warnings, projectWarnings...)
+	warnings = append(warnings, boardWarnings...)
+
+	actions := cardsToActions(allCards, sourcesByCardID, boardsByID)
+	SortActions(actions, f.Sort, time.Now())
+	return actions, warnings, nil
+}
+
+// fetchListCards returns the cards on a single list, such as the Waiting For list
+func (f *Fetcher) fetchListCards(ctx context.Context, listID string) ([]trello.Card, error) {
+	cards, err := f.Client.CardsOnList(f.Include.context(ctx), listID)
 	if err != nil {
 		return nil, err
 	}
-	allCards = append(allCards, projectTodoCards...)
+	return f.Include.filter(cards), nil
+}
 
-	boardsByID, err := f.fetchAllBoards(allCards)
+// listCardsToActions returns an action for each of the cards from fetchListCards, all of which have the same source
+func (f *Fetcher) listCardsToActions(
+	ctx context.Context,
+	cards []trello.Card,
+	source string,
+) ([]Action, []Warning, error) {
+	boardsByID, warnings, err := f.fetchAllBoards(ctx, cards)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return cardsToActions(allCards, boardsByID), nil
+	sourcesByCardID := make(map[string][]string)
+	for i := range cards {
+		sourcesByCardID[cards[i].ID] = []string{source}
+	}
+	return cardsToActions(cards, sourcesByCardID, boardsByID), warnings, nil
 }
 
-func (f *Fetcher) fetchOwnedCards() ([]trello.Card, error) {
-	return f.Client.OwnedCards()
+func (f *Fetcher) fetchOwnedCards(ctx context.Context) ([]trello.Card, error) {
+	ownedCards, err := f.Client.OwnedCards(f.Include.context(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Include.filter(ownedCards), nil
 }
 
-func (f *Fetcher) fetchCardsOnNextActionsList() ([]trello.Card, error) {
-	return f.Client.CardsOnList(f.Config.TrelloNextActionsListID)
+func (f *Fetcher) fetchCardsOnNextActionsList(ctx context.Context) ([]trello.Card, error) {
+	cards, err := f.Client.CardsOnList(f.Include.context(ctx), f.Config.TrelloNextActionsListID)
+	if err != nil {
+		return nil, err
+	}
+	return f.Include.filter(cards), nil
 }
 
-func (f *Fetcher) fetchProjectTodoListCards() ([]trello.Card, error) {
+// fetchProjectTodoListCards returns the first cards on the Todo list of each project, see projectActionCards. Rather
+// than making requests for each project separately, the lists on every project board are fetched in batches, followed
+// by the cards on every Todo list.
+func (f *Fetcher) fetchProjectTodoListCards(ctx context.Context) ([]trello.Card, []Warning, error) {
 	allCards := make([]trello.Card, 0)
+	warnings := make([]Warning, 0)
 
-	projectCards, err := f.Client.CardsOnList(f.Config.TrelloProjectsListID)
+	projectCards, err := f.Client.CardsWithAttachmentsOnList(ctx, f.Config.TrelloProjectsListID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	todoListCardsChannel := make(chan []trello.Card)
-	errorsChannel := make(chan error)
+	boardIDsByProjectCardID := make(map[string]string)
+	for i := range projectCards {
+		projectCard := &projectCards[i]
+		projectBoardID, err := getProjectBoardID(projectCard)
+		if err != nil {
+			warnings = append(warnings, *newProjectWarning(projectCard, "", err))
+			continue
+		}
+		boardIDsByProjectCardID[projectCard.ID] = projectBoardID
+	}
 
+	listsByBoardID, listErrorsByBoardID := f.fetchListsOnBoards(ctx, uniqueValues(boardIDsByProjectCardID))
+
+	todoListIDsByProjectCardID := make(map[string]string)
 	for i := range projectCards {
 		projectCard := &projectCards[i]
-		go f.fetchProjectTodoList(projectCard, todoListCardsChannel, errorsChannel)
+		projectBoardID, ok := boardIDsByProjectCardID[projectCard.ID]
+		if !ok {
+			continue
+		}
+		if err := listErrorsByBoardID[projectBoardID]; err != nil {
+			warnings = append(warnings, *newProjectWarning(projectCard, projectBoardID, err))
+			continue
+		}
+		todoList, err := getTodoList(listsByBoardID[projectBoardID], todoListNames(f.Config, projectCard))
+		if err != nil {
+			warnings = append(warnings, *newProjectWarning(projectCard, projectBoardID, err))
+			continue
+		}
+		todoListIDsByProjectCardID[projectCard.ID] = todoList.ID
 	}
 
-	for range projectCards {
-		select {
-		case todoListCards := <-todoListCardsChannel:
-			if len(todoListCards) > 0 {
-				allCards = append(allCards, todoListCards[0])
-			}
-		case err := <-errorsChannel:
-			return nil, err
+	cardsByListID, cardErrorsByListID := f.fetchCardsOnLists(ctx, uniqueValues(todoListIDsByProjectCardID))
+
+	for i := range projectCards {
+		projectCard := &projectCards[i]
+		todoListID, ok := todoListIDsByProjectCardID[projectCard.ID]
+		if !ok {
+			continue
 		}
+		if err := cardErrorsByListID[todoListID]; err != nil {
+			warnings = append(warnings, *newProjectWarning(projectCard, boardIDsByProjectCardID[projectCard.ID], err))
+			continue
+		}
+		todoListCards := f.Include.filter(cardsByListID[todoListID])
+		allCards = append(allCards, projectActionCards(f.Config, todoListCards)...)
+	}
+
+	// Requests that failed because we were cancelled are not warnings, the whole fetch has failed
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
 	}
 
-	return allCards, nil
+	return allCards, warnings, nil
 }
 
-func (f *Fetcher) fetchProjectTodoList(
-	projectCard *trello.Card,
-	todoListCardsChannel chan []trello.Card,
-	errorsChannel chan error,
-) {
-	projectBoardID, err := getProjectBoardID(projectCard)
-	if err != nil {
-		errorsChannel <- err
-		return
-	}
-	projectLists, err := f.Client.ListsOnBoard(projectBoardID)
-	if err != nil {
-		errorsChannel <- err
-		return
+func (f *Fetcher) fetchAllBoards(
+	ctx context.Context,
+	cards []trello.Card,
+) (map[string]*trello.Board, []Warning, error) {
+	boardIDsByCardID := make(map[string]string)
+	for i := range cards {
+		boardIDsByCardID[cards[i].ID] = cards[i].BoardID
 	}
 
-	todoList, err := getTodoList(projectLists)
-	if err != nil {
-		errorsChannel <- err
-		return
+	boardsByID, errorsByBoardID := f.fetchBoards(ctx, uniqueValues(boardIDsByCardID))
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
 	}
-	todoListCards, err := f.Client.CardsOnList(todoList.ID)
-	if err != nil {
-		errorsChannel <- err
-		return
+
+	warnings := make([]Warning, 0)
+	for _, boardID := range uniqueValues(boardIDsByCardID) {
+		if err, ok := errorsByBoardID[boardID]; ok {
+			warnings = append(warnings, *newBoardWarning(boardID, err))
+		}
 	}
 
-	todoListCardsChannel <- todoListCards
+	return boardsByID, warnings, nil
 }
 
-func (f *Fetcher) fetchAllBoards(cards []trello.Card) (map[string]*trello.Board, error) {
-	boardsChannel := make(chan *trello.Board)
-	errorsChannel := make(chan error)
+func (f *Fetcher) fetchListsOnBoards(
+	ctx context.Context,
+	boardIDs []string,
+) (map[string][]trello.List, map[string]error) {
+	var mutex sync.Mutex
+	listsByBoardID := make(map[string][]trello.List)
 
-	uniqueBoardIDs := make(map[string]interface{})
-	for i := range cards {
-		uniqueBoardIDs[cards[i].BoardID] = nil
-	}
+	errorsByBoardID := inBatches(boardIDs, func(batchBoardIDs []string) (map[string]error, error) {
+		batchListsByBoardID, batchErrorsByBoardID, err := f.Client.ListsOnBoards(ctx, batchBoardIDs)
 
-	for boardID := range uniqueBoardIDs {
-		go f.fetchBoard(boardID, boardsChannel, errorsChannel)
-	}
+		mutex.Lock()
+		defer mutex.Unlock()
+		for boardID, lists := range batchListsByBoardID {
+			listsByBoardID[boardID] = lists
+		}
+		return batchErrorsByBoardID, err
+	})
+
+	return listsByBoardID, errorsByBoardID
+}
+
+func (f *Fetcher) fetchCardsOnLists(
+	ctx context.Context,
+	listIDs []string,
+) (map[string][]trello.Card, map[string]error) {
+	var mutex sync.Mutex
+	cardsByListID := make(map[string][]trello.Card)
+
+	errorsByListID := inBatches(listIDs, func(batchListIDs []string) (map[string]error, error) {
+		fetchCards := f.Client.CardsOnLists
+		if f.Config.TrelloSkipBlockedActions {
+			// Checklists are needed to tell whether a card's dependencies are complete
+			fetchCards = f.Client.CardsWithChecklistsOnLists
+		}
+		batchCardsByListID, batchErrorsByListID, err := fetchCards(f.Include.context(ctx), batchListIDs)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		for listID, cards := range batchCardsByListID {
+			cardsByListID[listID] = cards
+		}
+		return batchErrorsByListID, err
+	})
+
+	return cardsByListID, errorsByListID
+}
 
+func (f *Fetcher) fetchBoards(ctx context.Context, boardIDs []string) (map[string]*trello.Board, map[string]error) {
+	var mutex sync.Mutex
 	boardsByID := make(map[string]*trello.Board)
 
-	for range uniqueBoardIDs {
-		select {
-		case board := <-boardsChannel:
-			boardsByID[board.ID] = board
-		case err := <-errorsChannel:
-			return nil, err
+	errorsByBoardID := inBatches(boardIDs, func(batchBoardIDs []string) (map[string]error, error) {
+		batchBoardsByID, batchErrorsByBoardID, err := f.Client.GetBoards(ctx, batchBoardIDs)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		for boardID, board := range batchBoardsByID {
+			boardsByID[boardID] = board
 		}
-	}
+		return batchErrorsByBoardID, err
+	})
 
-	return boardsByID, nil
+	return boardsByID, errorsByBoardID
 }
 
-func (f *Fetcher) fetchBoard(boardID string, boardsChannel chan *trello.Board, errorsChannel chan error) {
-	board, err := f.Client.GetBoard(boardID)
-	if err != nil {
-		errorsChannel <- err
-		return
+// inBatches splits the IDs up into batches small enough for a single Trello batch request, then calls fetchBatch for
+// each of them concurrently. Once they have all finished, the errors for each ID are returned - if a whole batch
+// failed then every ID in that batch is given the same error.
+func inBatches(ids []string, fetchBatch func(batchIDs []string) (map[string]error, error)) map[string]error {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	errorsByID := make(map[string]error)
+
+	for start := 0; start < len(ids); start += trello.MaxBatchSize {
+		end := start + trello.MaxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		wg.Add(1)
+		go func(batchIDs []string) {
+			defer wg.Done()
+
+			batchErrorsByID, err := fetchBatch(batchIDs)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, id := range batchIDs {
+				if err != nil {
+					errorsByID[id] = err
+				} else if batchErrorsByID[id] != nil {
+					errorsByID[id] = batchErrorsByID[id]
+				}
+			}
+		}(ids[start:end])
 	}
 
-	boardsChannel <- board
+	wg.Wait()
+
+	return errorsByID
 }
 
+// uniqueValues returns each distinct value in the map once, in a consistent order
+func uniqueValues(valuesByKey map[string]string) []string {
+	seen := make(map[string]bool)
+	values := make([]string, 0)
+	for _, value := range valuesByKey {
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// getProjectBoardID finds the board a project card links to, which is usually attached to the card but may instead be
+// linked from its description or name. The board is identified by either its short link or its full ID, both of which
+// Trello accepts in place of the other.
 func getProjectBoardID(projectCard *trello.Card) (string, error) {
-	boardIDRegex, err := regexp.Compile(regexp.QuoteMeta(trello.BoardBaseURL) + `(\w+).*`)
+	boardIDRegex, err := regexp.Compile(regexp.QuoteMeta(trello.BoardBaseURL) + `(\w+)`)
 	if err != nil {
 		return "", err
 	}
 
-	matches := boardIDRegex.FindStringSubmatch(projectCard.Name)
-	if len(matches) != 2 {
-		return "", fmt.Errorf("could not parse board ID from card name %s", projectCard.Name)
+	places := make([]string, 0, len(projectCard.Attachments)+2)
+	for i := range projectCard.Attachments {
+		places = append(places, projectCard.Attachments[i].URL.String())
+	}
+	places = append(places, projectCard.Description, projectCard.Name)
+
+	for _, place := range places {
+		if matches := boardIDRegex.FindStringSubmatch(place); len(matches) == 2 {
+			return matches[1], nil
+		}
 	}
-	return matches[1], nil
+	return "", fmt.Errorf(
+		"could not find a board link in the attachments, description or name of card %s",
+		projectCard.Name,
+	)
 }
 
-func getTodoList(lists []trello.List) (*trello.List, error) {
-	for _, list := range lists {
-		if list.Name == "Todo" {
-			return &list, nil
+// mergeSources combines the cards from each source, so that a card found in more than one source is only returned
+// once. The sources each card was found in are also returned, keyed by card ID.
+func mergeSources(cardsBySource map[string][]trello.Card) ([]trello.Card, map[string][]string) {
+	cards := make([]trello.Card, 0)
+	sourcesByCardID := make(map[string][]string)
+
+	// Sources are merged in a fixed order, so that cards keep the order they would have without duplicates
+	for _, source := range []string{ActionSourceOwned, ActionSourceNextActionsList, ActionSourceProjectTodo} {
+		for _, card := range cardsBySource[source] {
+			sources, ok := sourcesByCardID[card.ID]
+			if !ok {
+				cards = append(cards, card)
+			}
+			// A card can be found more than once in the same source, e.g. if two projects link to the same board.
+			// Sources are merged one at a time, so it would already be the card's latest source.
+			if len(sources) > 0 && sources[len(sources)-1] == source {
+				continue
+			}
+			sourcesByCardID[card.ID] = append(sources, source)
 		}
 	}
-	return nil, fmt.Errorf("missing Todo list on board")
+	return cards, sourcesByCardID
 }
 
-func cardsToActions(cards []trello.Card, boardsByID map[string]*trello.Board) []Action {
+func cardsToActions(
+	cards []trello.Card,
+	sourcesByCardID map[string][]string,
+	boardsByID map[string]*trello.Board,
+) []Action {
 	actions := make([]Action, 0)
 	for i := range cards {
 		card := &cards[i]
-		actions = append(actions, Action{
-			ID:          card.ID,
-			Name:        card.Name,
-			DueBy:       card.DueBy,
-			URL:         card.URL,
-			ImageURL:    getImageURL(card, boardsByID),
-			ProjectName: boardsByID[card.BoardID].Name,
-		})
+		action := Action{
+			ID:       card.ID,
+			Name:     card.Name,
+			DueBy:    card.DueBy,
+			URL:      card.URL,
+			Position: card.Position,
+			Sources:  sourcesByCardID[card.ID],
+			Labels:   cardLabels(card),
+		}
+		// If the board could not be fetched we still return the action, just without any project details
+		if board, ok := boardsByID[card.BoardID]; ok {
+			action.ImageURL = getImageURL(board)
+			action.ProjectName = board.Name
+		}
+		actions = append(actions, action)
 	}
 	return actions
 }
 
-func getImageURL(card *trello.Card, boardsByID map[string]*trello.Board) *url.URL {
-	cardImages := boardsByID[card.BoardID].Preferences.BackgroundImages
-	if len(cardImages) == 0 {
+func getImageURL(board *trello.Board) *url.URL {
+	boardImages := board.Preferences.BackgroundImages
+	if len(boardImages) == 0 {
 		return nil
 	}
-	return &cardImages[0].URL
+	return &boardImages[0].URL
 }
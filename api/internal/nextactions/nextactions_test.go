@@ -1,8 +1,11 @@
 package nextactions
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,6 +17,9 @@ func testConfig() *config.Config {
 	return &config.Config{
 		TrelloNextActionsListID: "nextActionsListId",
 		TrelloProjectsListID:    "projectsListId",
+		TrelloTodoListNames:     config.DefaultTrelloTodoListNames(),
+		TrelloActionsPerProject: config.DefaultTrelloActionsPerProject,
+		TrelloBlockedLabelNames: config.DefaultTrelloBlockedLabelNames(),
 	}
 }
 
@@ -23,23 +29,162 @@ func testImageURL(size string) *url.URL {
 }
 
 type fakeTrelloClient struct {
-	ownedCards         []trello.Card
-	cardsOnLists       map[string][]trello.Card
-	listsOnBoards      map[string][]trello.List
-	ownedCardsError    error
-	cardsOnListErrors  map[string]error
-	listsOnBoardErrors map[string]error
-	boards             map[string]*trello.Board
+	currentMemberError  error
+	ownedCards          []trello.Card
+	cardsOnLists        map[string][]trello.Card
+	listsOnBoards       map[string][]trello.List
+	ownedCardsError     error
+	cardsOnListErrors   map[string]error
+	listsOnBoardErrors  map[string]error
+	blockingBoardIDs    map[string]bool
+	boards              map[string]*trello.Board
+	cards               map[string]*trello.Card
+	dueCompleteCardIDs  []string
+	archivedCardIDs     []string
+	movedCardListIDs    map[string]string
+	requestDelay        time.Duration
+	requestsMutex       sync.Mutex
+	requestsInFlight    int
+	maxRequestsInFlight int
+	batchRequestCount   int
 }
 
-func (f *fakeTrelloClient) OwnedCards() ([]trello.Card, error) {
+// trackRequest waits for a slot from the context's request limiter, as the real client does, then records a request
+// as being in flight until the returned function is called
+func (f *fakeTrelloClient) trackRequest(ctx context.Context) func() {
+	release, err := trello.AcquireRequestSlot(ctx)
+	if err != nil {
+		return func() {}
+	}
+
+	f.requestsMutex.Lock()
+	f.requestsInFlight++
+	if f.requestsInFlight > f.maxRequestsInFlight {
+		f.maxRequestsInFlight = f.requestsInFlight
+	}
+	f.requestsMutex.Unlock()
+
+	time.Sleep(f.requestDelay)
+
+	return func() {
+		f.requestsMutex.Lock()
+		f.requestsInFlight--
+		f.requestsMutex.Unlock()
+		release()
+	}
+}
+
+// trackBatchRequest records a batch request, as well as tracking it as being in flight
+func (f *fakeTrelloClient) trackBatchRequest(ctx context.Context) func() {
+	f.requestsMutex.Lock()
+	f.batchRequestCount++
+	f.requestsMutex.Unlock()
+
+	return f.trackRequest(ctx)
+}
+
+func (f *fakeTrelloClient) CurrentMember(ctx context.Context) (*trello.Member, error) {
+	if f.currentMemberError != nil {
+		return nil, f.currentMemberError
+	}
+	return &trello.Member{ID: "memberId", Username: "someone", FullName: "Someone"}, nil
+}
+
+func (f *fakeTrelloClient) OwnedCards(ctx context.Context) ([]trello.Card, error) {
+	defer f.trackRequest(ctx)()
+
 	if f.ownedCardsError != nil {
 		return nil, f.ownedCardsError
 	}
 	return f.ownedCards, nil
 }
 
-func (f *fakeTrelloClient) CardsOnList(listID string) ([]trello.Card, error) {
+func (f *fakeTrelloClient) CardsOnList(ctx context.Context, listID string) ([]trello.Card, error) {
+	defer f.trackRequest(ctx)()
+
+	return f.cardsOnList(listID)
+}
+
+func (f *fakeTrelloClient) CardsWithAttachmentsOnList(ctx context.Context, listID string) ([]trello.Card, error) {
+	defer f.trackRequest(ctx)()
+
+	return f.cardsOnList(listID)
+}
+
+func (f *fakeTrelloClient) ListsOnBoard(ctx context.Context, boardID string) ([]trello.List, error) {
+	defer f.trackRequest(ctx)()
+
+	return f.listsOnBoard(ctx, boardID)
+}
+
+func (f *fakeTrelloClient) CardsOnLists(
+	ctx context.Context,
+	listIDs []string,
+) (map[string][]trello.Card, map[string]error, error) {
+	defer f.trackBatchRequest(ctx)()
+
+	cardsByListID := make(map[string][]trello.Card)
+	errorsByListID := make(map[string]error)
+	for _, listID := range listIDs {
+		cards, err := f.cardsOnList(listID)
+		if err != nil {
+			errorsByListID[listID] = err
+			continue
+		}
+		cardsByListID[listID] = cards
+	}
+	return cardsByListID, errorsByListID, nil
+}
+
+func (f *fakeTrelloClient) CardsWithChecklistsOnLists(
+	ctx context.Context,
+	listIDs []string,
+) (map[string][]trello.Card, map[string]error, error) {
+	return f.CardsOnLists(ctx, listIDs)
+}
+
+func (f *fakeTrelloClient) ListsOnBoards(
+	ctx context.Context,
+	boardIDs []string,
+) (map[string][]trello.List, map[string]error, error) {
+	defer f.trackBatchRequest(ctx)()
+
+	listsByBoardID := make(map[string][]trello.List)
+	errorsByBoardID := make(map[string]error)
+	for _, boardID := range boardIDs {
+		lists, err := f.listsOnBoard(ctx, boardID)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if err != nil {
+			errorsByBoardID[boardID] = err
+			continue
+		}
+		listsByBoardID[boardID] = lists
+	}
+	return listsByBoardID, errorsByBoardID, nil
+}
+
+func (f *fakeTrelloClient) GetBoards(
+	ctx context.Context,
+	boardIDs []string,
+) (map[string]*trello.Board, map[string]error, error) {
+	defer f.trackBatchRequest(ctx)()
+
+	boardsByID := make(map[string]*trello.Board)
+	errorsByBoardID := make(map[string]error)
+	for _, boardID := range boardIDs {
+		board, ok := f.boards[boardID]
+		if !ok {
+			errorsByBoardID[boardID] = fmt.Errorf("board with id %s not found", boardID)
+			continue
+		}
+		boardsByID[boardID] = board
+	}
+	return boardsByID, errorsByBoardID, nil
+}
+
+func (f *fakeTrelloClient) cardsOnList(listID string) ([]trello.Card, error) {
 	if f.cardsOnListErrors[listID] != nil {
 		return nil, f.cardsOnListErrors[listID]
 	}
@@ -50,7 +195,11 @@ func (f *fakeTrelloClient) CardsOnList(listID string) ([]trello.Card, error) {
 	return cards, nil
 }
 
-func (f *fakeTrelloClient) ListsOnBoard(boardID string) ([]trello.List, error) {
+func (f *fakeTrelloClient) listsOnBoard(ctx context.Context, boardID string) ([]trello.List, error) {
+	if f.blockingBoardIDs[boardID] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
 	if f.listsOnBoardErrors[boardID] != nil {
 		return nil, f.listsOnBoardErrors[boardID]
 	}
@@ -61,12 +210,31 @@ func (f *fakeTrelloClient) ListsOnBoard(boardID string) ([]trello.List, error) {
 	return lists, nil
 }
 
-func (f *fakeTrelloClient) GetBoard(boardID string) (*trello.Board, error) {
-	board, ok := f.boards[boardID]
+func (f *fakeTrelloClient) GetCard(ctx context.Context, cardID string) (*trello.Card, error) {
+	card, ok := f.cards[cardID]
 	if !ok {
-		return nil, fmt.Errorf("board with id %s not found", boardID)
+		return nil, fmt.Errorf("card with id %s not found", cardID)
 	}
-	return board, nil
+	return card, nil
+}
+
+func (f *fakeTrelloClient) MarkCardDueComplete(ctx context.Context, cardID string) error {
+	f.dueCompleteCardIDs = append(f.dueCompleteCardIDs, cardID)
+	return nil
+}
+
+func (f *fakeTrelloClient) ArchiveCard(ctx context.Context, cardID string) error {
+	f.archivedCardIDs = append(f.archivedCardIDs, cardID)
+	return nil
+}
+
+func (f *fakeTrelloClient) MoveCardToList(ctx context.Context, cardID, listID string) error {
+	f.movedCardListIDs[cardID] = listID
+	return nil
+}
+
+func (f *fakeTrelloClient) AddCard(card *trello.Card) {
+	f.cards[card.ID] = card
 }
 
 func (f *fakeTrelloClient) AddOwnedCard(card *trello.Card) {
@@ -85,6 +253,10 @@ func (f *fakeTrelloClient) AddBoard(board *trello.Board) {
 	f.boards[board.ID] = board
 }
 
+func (f *fakeTrelloClient) SetCurrentMemberError(err error) {
+	f.currentMemberError = err
+}
+
 func (f *fakeTrelloClient) SetOwnedCardsError(err error) {
 	f.ownedCardsError = err
 }
@@ -97,13 +269,20 @@ func (f *fakeTrelloClient) SetListsOnBoardError(boardID string, err error) {
 	f.listsOnBoardErrors[boardID] = err
 }
 
+func (f *fakeTrelloClient) SetListsOnBoardBlocks(boardID string) {
+	f.blockingBoardIDs[boardID] = true
+}
+
 func newFakeTrelloClient() *fakeTrelloClient {
 	client := &fakeTrelloClient{
 		cardsOnLists:       make(map[string][]trello.Card),
 		listsOnBoards:      make(map[string][]trello.List),
 		cardsOnListErrors:  make(map[string]error),
 		listsOnBoardErrors: make(map[string]error),
+		blockingBoardIDs:   make(map[string]bool),
 		boards:             make(map[string]*trello.Board),
+		cards:              make(map[string]*trello.Card),
+		movedCardListIDs:   make(map[string]string),
 	}
 
 	client.AddBoard(&trello.Board{
@@ -120,6 +299,150 @@ func newFakeTrelloClient() *fakeTrelloClient {
 	return client
 }
 
+// fakeTrelloServer is a stateful Trello server for end-to-end tests, set up with the lists from testConfig on an
+// inbox board, and a single project board with Todo and Done lists
+type fakeTrelloServer struct {
+	*trello.FakeServer
+	cfg            *config.Config
+	projectBoardID string
+	todoListID     string
+	doneListID     string
+}
+
+func newFakeTrelloServer(t *testing.T) *fakeTrelloServer {
+	fake := trello.NewFakeServer("some key", "some token")
+	t.Cleanup(fake.Close)
+
+	inboxBoardID := fake.AddBoard(trello.FakeBoard{Name: "Inbox"})
+	nextActionsListID := fake.AddList(trello.FakeList{BoardID: inboxBoardID, Name: "Next Actions"})
+	projectsListID := fake.AddList(trello.FakeList{BoardID: inboxBoardID, Name: "Projects"})
+
+	projectBoardID := fake.AddBoard(trello.FakeBoard{
+		Name:                "My Project",
+		BackgroundImageURLs: []string{testImageURL("75x100").String(), testImageURL("144x192").String()},
+	})
+	todoListID := fake.AddList(trello.FakeList{BoardID: projectBoardID, Name: "Todo"})
+	doneListID := fake.AddList(trello.FakeList{BoardID: projectBoardID, Name: "Done"})
+
+	projectBoard, _ := fake.Board(projectBoardID)
+	projectURL := trello.BoardBaseURL + projectBoard.ShortLink + "/my-project"
+	fake.AddCard(trello.FakeCard{ListID: projectsListID, Name: projectURL})
+
+	cfg := &config.Config{
+		TrelloNextActionsListID: nextActionsListID,
+		TrelloProjectsListID:    projectsListID,
+		TrelloTodoListNames:     config.DefaultTrelloTodoListNames(),
+		TrelloActionsPerProject: config.DefaultTrelloActionsPerProject,
+		TrelloBlockedLabelNames: config.DefaultTrelloBlockedLabelNames(),
+	}
+
+	return &fakeTrelloServer{
+		FakeServer:     fake,
+		cfg:            cfg,
+		projectBoardID: projectBoardID,
+		todoListID:     todoListID,
+		doneListID:     doneListID,
+	}
+}
+
+func actionIDs(actions []Action) []string {
+	ids := make([]string, len(actions))
+	for i := range actions {
+		ids[i] = actions[i].ID
+	}
+	return ids
+}
+
+func TestFetchAgainstFakeTrello(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	ownedCardID := fake.AddOwnedCard(trello.FakeCard{ListID: fake.doneListID, Name: "Owned Action"})
+	nextActionID := fake.AddCard(trello.FakeCard{ListID: fake.cfg.TrelloNextActionsListID, Name: "Next Action"})
+	todoCardID := fake.AddCard(trello.FakeCard{ListID: fake.todoListID, Name: "First Todo"})
+	fake.AddCard(trello.FakeCard{ListID: fake.todoListID, Name: "Second Todo"})
+
+	fetcher := Fetcher{Client: fake.Client(), Config: fake.cfg}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	assertCardIDsMatchExpected(t, actionIDs(actions), []string{ownedCardID, nextActionID, todoCardID})
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+	if actions[2].ProjectName != "My Project" || actions[2].ImageURL.String() != testImageURL("75x100").String() {
+		t.Errorf("Expected project action with project name and image, got %+v", actions[2])
+	}
+}
+
+func TestFetchAgainstFakeTrelloFindsProjectBoardFromAttachment(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	boardID := fake.AddBoard(trello.FakeBoard{Name: "Attached Project"})
+	todoListID := fake.AddList(trello.FakeList{BoardID: boardID, Name: "Todo"})
+	todoCardID := fake.AddCard(trello.FakeCard{ListID: todoListID, Name: "Attached Todo"})
+	board, _ := fake.Board(boardID)
+	fake.AddCard(trello.FakeCard{
+		ListID:      fake.cfg.TrelloProjectsListID,
+		Name:        "Attached Project",
+		Attachments: []trello.FakeAttachment{{Name: "Board", URL: trello.BoardBaseURL + board.ShortLink}},
+	})
+
+	fetcher := Fetcher{Client: fake.Client(), Config: fake.cfg}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(actions) != 1 || actions[0].ID != todoCardID || actions[0].ProjectName != "Attached Project" {
+		t.Errorf("Expected only action %s from the attached board, got %+v", todoCardID, actions)
+	}
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestFetchAgainstFakeTrelloSkipsCardsWithIncompleteDependencies(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	blockedCardID := fake.AddCard(trello.FakeCard{ListID: fake.todoListID, Name: "Blocked Todo"})
+	fake.AddChecklist(trello.FakeChecklist{
+		CardID: blockedCardID,
+		Name:   "Dependencies",
+		Items:  []trello.FakeCheckItem{{Name: "Other card", Complete: false}},
+	})
+	readyCardID := fake.AddCard(trello.FakeCard{ListID: fake.todoListID, Name: "Ready Todo"})
+
+	cfg := *fake.cfg
+	cfg.TrelloSkipBlockedActions = true
+
+	fetcher := Fetcher{Client: fake.Client(), Config: &cfg}
+	actions, _, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(actions) != 1 || actions[0].ID != readyCardID {
+		t.Errorf("Expected only action %s, got %+v", readyCardID, actions)
+	}
+}
+
+func TestFetchAgainstFakeTrelloRetriesServerErrors(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	fake.AddCard(trello.FakeCard{ListID: fake.todoListID, Name: "First Todo"})
+	fake.InjectErrors("/batch", nil, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+
+	client := fake.Client(trello.WithRetryPolicy(&trello.RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond}))
+
+	fetcher := Fetcher{Client: client, Config: fake.cfg}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(actions) != 1 {
+		t.Errorf("Expected %d actions, got %d", 1, len(actions))
+	}
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
 func TestOwnedCardsAreReturnedAsActions(t *testing.T) {
 	cardURL, _ := url.Parse("https://example.com")
 	ownedCard := trello.Card{ID: "an id", Name: "a name", URL: *cardURL, BoardID: "boardId"}
@@ -127,8 +450,8 @@ func TestOwnedCardsAreReturnedAsActions(t *testing.T) {
 	fakeClient := newFakeTrelloClient()
 	fakeClient.AddOwnedCard(&ownedCard)
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
 	expectedActions := []Action{
 		{ID: "an id", Name: "a name", URL: *cardURL, ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
@@ -138,6 +461,23 @@ func TestOwnedCardsAreReturnedAsActions(t *testing.T) {
 		t.Errorf("Unexpected error: %s", err)
 	}
 	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestOwnedCardsWithCompleteDueDatesAreNotReturned(t *testing.T) {
+	ownedCard := trello.Card{ID: "an id", Name: "a name", DueComplete: true, BoardID: "boardId"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddOwnedCard(&ownedCard)
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, []Action{})
+	assertWarningsMatchExpected(t, warnings, []Warning{})
 }
 
 func TestErrorWithOwnedCardsReturnsError(t *testing.T) {
@@ -146,8 +486,31 @@ func TestErrorWithOwnedCardsReturnsError(t *testing.T) {
 	fakeClient := newFakeTrelloClient()
 	fakeClient.SetOwnedCardsError(expectedError)
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	if err != expectedError {
+		t.Errorf("Expected error %s, got %s", expectedError, err)
+	}
+	if actions != nil {
+		t.Errorf("Expected no actions, got %+v", actions)
+	}
+	if warnings != nil {
+		t.Errorf("Expected no warnings, got %+v", warnings)
+	}
+}
+
+func TestErrorWithOwnedCardsCancelsOutstandingRequests(t *testing.T) {
+	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/slowBoardId"}
+	expectedError := fmt.Errorf("an error")
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.SetOwnedCardsError(expectedError)
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+	fakeClient.SetListsOnBoardBlocks("slowBoardId")
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
 	if err != expectedError {
 		t.Errorf("Expected error %s, got %s", expectedError, err)
@@ -155,6 +518,85 @@ func TestErrorWithOwnedCardsReturnsError(t *testing.T) {
 	if actions != nil {
 		t.Errorf("Expected no actions, got %+v", actions)
 	}
+	if warnings != nil {
+		t.Errorf("Expected no warnings, got %+v", warnings)
+	}
+}
+
+func TestFetchReturnsErrorWhenDeadlineIsExceeded(t *testing.T) {
+	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/slowBoardId"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+	fakeClient.SetListsOnBoardBlocks("slowBoardId")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(ctx)
+
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected error %s, got %s", context.DeadlineExceeded, err)
+	}
+	if actions != nil {
+		t.Errorf("Expected no actions, got %+v", actions)
+	}
+	if warnings != nil {
+		t.Errorf("Expected no warnings, got %+v", warnings)
+	}
+}
+
+func TestConcurrentRequestsAreLimited(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	fakeClient.requestDelay = 5 * time.Millisecond
+	for i := 0; i < 10; i++ {
+		boardID := fmt.Sprintf("board%d", i)
+		fakeClient.AddCardOnList("projectsListId", &trello.Card{ID: boardID, Name: "https://trello.com/b/" + boardID})
+		fakeClient.AddListOnBoard(boardID, &trello.List{ID: boardID + "TodoList", Name: "Todo"})
+		fakeClient.AddCardOnList(boardID+"TodoList", &trello.Card{ID: boardID + "Card", BoardID: "boardId"})
+	}
+
+	cfg := testConfig()
+	cfg.TrelloMaxConcurrentRequests = 2
+
+	fetcher := Fetcher{Client: fakeClient, Config: cfg}
+	actions, _, err := fetcher.Fetch(context.Background())
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if len(actions) != 10 {
+		t.Errorf("Unexpected number of actions returned, expected %d and got %d", 10, len(actions))
+	}
+	if fakeClient.maxRequestsInFlight != 2 {
+		t.Errorf("Expected at most %d requests in flight, got %d", 2, fakeClient.maxRequestsInFlight)
+	}
+}
+
+func TestProjectsAndBoardsAreFetchedInBatches(t *testing.T) {
+	fakeClient := newFakeTrelloClient()
+	for i := 0; i < 2*trello.MaxBatchSize+1; i++ {
+		boardID := fmt.Sprintf("board%d", i)
+		fakeClient.AddCardOnList("projectsListId", &trello.Card{ID: boardID, Name: "https://trello.com/b/" + boardID})
+		fakeClient.AddListOnBoard(boardID, &trello.List{ID: boardID + "TodoList", Name: "Todo"})
+		fakeClient.AddCardOnList(boardID+"TodoList", &trello.Card{ID: boardID + "Card", BoardID: "boardId"})
+	}
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if len(actions) != 2*trello.MaxBatchSize+1 {
+		t.Errorf("Unexpected number of actions returned, expected %d and got %d", 2*trello.MaxBatchSize+1, len(actions))
+	}
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+	// 3 batches of lists on boards, 3 batches of cards on lists, then a single batch for the shared board
+	if fakeClient.batchRequestCount != 7 {
+		t.Errorf("Expected %d batch requests, got %d", 7, fakeClient.batchRequestCount)
+	}
 }
 
 func TestCardsInNextActionsListAreReturnedAsActions(t *testing.T) {
@@ -163,8 +605,8 @@ func TestCardsInNextActionsListAreReturnedAsActions(t *testing.T) {
 	fakeClient := newFakeTrelloClient()
 	fakeClient.AddCardOnList("nextActionsListId", &nextActionsCard)
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
 	expectedActions := []Action{
 		{ID: "an id", Name: "a name", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
@@ -174,6 +616,7 @@ func TestCardsInNextActionsListAreReturnedAsActions(t *testing.T) {
 		t.Errorf("Unexpected error: %s", err)
 	}
 	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
 }
 
 func TestErrorWithCardsOnListReturnsError(t *testing.T) {
@@ -182,8 +625,8 @@ func TestErrorWithCardsOnListReturnsError(t *testing.T) {
 	fakeClient := newFakeTrelloClient()
 	fakeClient.SetCardsOnListError("nextActionsListId", expectedError)
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
 	if err != expectedError {
 		t.Errorf("Expected error %s, got %s", expectedError, err)
@@ -191,6 +634,9 @@ func TestErrorWithCardsOnListReturnsError(t *testing.T) {
 	if actions != nil {
 		t.Errorf("Expected no actions, got %+v", actions)
 	}
+	if warnings != nil {
+		t.Errorf("Expected no warnings, got %+v", warnings)
+	}
 }
 
 func TestErrorWithCardsOnProjectsListReturnsError(t *testing.T) {
@@ -199,8 +645,8 @@ func TestErrorWithCardsOnProjectsListReturnsError(t *testing.T) {
 	fakeClient := newFakeTrelloClient()
 	fakeClient.SetCardsOnListError("projectsListId", expectedError)
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
 	if err != expectedError {
 		t.Errorf("Expected error %s, got %s", expectedError, err)
@@ -208,80 +654,204 @@ func TestErrorWithCardsOnProjectsListReturnsError(t *testing.T) {
 	if actions != nil {
 		t.Errorf("Expected no actions, got %+v", actions)
 	}
+	if warnings != nil {
+		t.Errorf("Expected no warnings, got %+v", warnings)
+	}
 }
 
-func TestInvalidNameOnProjectCardReturnsError(t *testing.T) {
+func TestInvalidNameOnProjectCardReturnsWarning(t *testing.T) {
 	brokenProjectCard := trello.Card{ID: "an id", Name: "invalid"}
 
 	fakeClient := newFakeTrelloClient()
 	fakeClient.AddCardOnList("projectsListId", &brokenProjectCard)
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
-	if err == nil {
-		t.Errorf("Expected error, got nil")
+	expectedWarnings := []Warning{
+		{
+			Source:          WarningSourceProject,
+			ProjectCardID:   "an id",
+			ProjectCardName: "invalid",
+			Detail:          "could not find a board link in the attachments, description or name of card invalid",
+		},
 	}
-	if actions != nil {
-		t.Errorf("Expected no actions, got %+v", actions)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, []Action{})
+	assertWarningsMatchExpected(t, warnings, expectedWarnings)
+}
+
+func TestProjectBoardIsFoundFromAttachmentsThenDescriptionThenName(t *testing.T) {
+	attachmentURL, _ := url.Parse("https://trello.com/b/attachedBoard/a-project")
+	otherAttachmentURL, _ := url.Parse("https://example.com/design.pdf")
+	fullBoardID := "5e3f6a7b8c9d0e1f2a3b4c5d"
+
+	tests := []struct {
+		description     string
+		card            trello.Card
+		expectedBoardID string
+	}{
+		{
+			"attachment",
+			trello.Card{
+				Name:        "https://trello.com/b/namedBoard",
+				Description: "See https://trello.com/b/describedBoard",
+				Attachments: []trello.Attachment{{URL: *otherAttachmentURL}, {URL: *attachmentURL}},
+			},
+			"attachedBoard",
+		},
+		{
+			"description",
+			trello.Card{Name: "https://trello.com/b/namedBoard", Description: "See https://trello.com/b/describedBoard"},
+			"describedBoard",
+		},
+		{"name", trello.Card{Name: "My Project https://trello.com/b/namedBoard/my-project"}, "namedBoard"},
+		{"full ID", trello.Card{Name: "My Project", Description: "https://trello.com/b/" + fullBoardID}, fullBoardID},
+	}
+
+	for _, test := range tests {
+		boardID, err := getProjectBoardID(&test.card)
+		if err != nil {
+			t.Errorf("Unexpected error finding board from %s: %s", test.description, err)
+		} else if boardID != test.expectedBoardID {
+			t.Errorf("Expected board %s from %s, got %s", test.expectedBoardID, test.description, boardID)
+		}
 	}
 }
 
-func TestErrorWithListsOnBoardReturnsError(t *testing.T) {
+func TestErrorWithListsOnBoardReturnsWarning(t *testing.T) {
 	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/broken/a-broken-card"}
-	expectedError := fmt.Errorf("an error")
 
 	fakeClient := newFakeTrelloClient()
 	fakeClient.AddCardOnList("projectsListId", &projectCard)
-	fakeClient.SetListsOnBoardError("broken", expectedError)
-
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
-
-	if err != expectedError {
-		t.Errorf("Expected error %s, got %s", expectedError, err)
+	fakeClient.SetListsOnBoardError("broken", fmt.Errorf("an error"))
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedWarnings := []Warning{
+		{
+			Source:          WarningSourceProject,
+			ProjectCardID:   "an id",
+			ProjectCardName: "https://trello.com/b/broken/a-broken-card",
+			BoardID:         "broken",
+			Detail:          "an error",
+		},
 	}
-	if actions != nil {
-		t.Errorf("Expected no actions, got %+v", actions)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
 	}
+	assertActionsMatchExpected(t, actions, []Action{})
+	assertWarningsMatchExpected(t, warnings, expectedWarnings)
 }
 
-func TestMissingTodoListOnProjectBoardReturnsError(t *testing.T) {
+func TestMissingTodoListOnProjectBoardReturnsWarning(t *testing.T) {
 	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/empty"}
 
 	fakeClient := newFakeTrelloClient()
 	fakeClient.AddCardOnList("projectsListId", &projectCard)
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedWarnings := []Warning{
+		{
+			Source:          WarningSourceProject,
+			ProjectCardID:   "an id",
+			ProjectCardName: "https://trello.com/b/empty",
+			BoardID:         "empty",
+			Detail:          "missing Todo list on board",
+		},
+	}
 
-	if err == nil {
-		t.Errorf("Expected error, got nil")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
 	}
-	if actions != nil {
-		t.Errorf("Expected no actions, got %+v", actions)
+	assertActionsMatchExpected(t, actions, []Action{})
+	assertWarningsMatchExpected(t, warnings, expectedWarnings)
+}
+
+func TestErrorWithTodoListReturnsWarning(t *testing.T) {
+	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/aBoardId"}
+	todoList := trello.List{ID: "todoListId", Name: "Todo"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+	fakeClient.AddListOnBoard("aBoardId", &todoList)
+	fakeClient.SetCardsOnListError("todoListId", fmt.Errorf("an error"))
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedWarnings := []Warning{
+		{
+			Source:          WarningSourceProject,
+			ProjectCardID:   "an id",
+			ProjectCardName: "https://trello.com/b/aBoardId",
+			BoardID:         "aBoardId",
+			Detail:          "an error",
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
 	}
+	assertActionsMatchExpected(t, actions, []Action{})
+	assertWarningsMatchExpected(t, warnings, expectedWarnings)
 }
 
-func TestErrorWithTodoListReturnsError(t *testing.T) {
+func TestBrokenProjectDoesNotPreventOtherActionsBeingReturned(t *testing.T) {
+	brokenProjectCard := trello.Card{ID: "a broken id", Name: "https://trello.com/b/empty"}
 	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/aBoardId"}
 	todoList := trello.List{ID: "todoListId", Name: "Todo"}
-	expectedError := fmt.Errorf("an error")
 
 	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &brokenProjectCard)
 	fakeClient.AddCardOnList("projectsListId", &projectCard)
 	fakeClient.AddListOnBoard("aBoardId", &todoList)
-	fakeClient.SetCardsOnListError("todoListId", expectedError)
+	fakeClient.AddCardOnList("todoListId", &trello.Card{ID: "a todo id", Name: "a name", BoardID: "boardId"})
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
-	if err != expectedError {
-		t.Errorf("Expected error %s, got %s", expectedError, err)
+	expectedActions := []Action{
+		{ID: "a todo id", Name: "a name", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
 	}
-	if actions != nil {
-		t.Errorf("Expected no actions, got %+v", actions)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	if len(warnings) != 1 || warnings[0].ProjectCardID != "a broken id" {
+		t.Errorf("Expected a single warning for the broken project, got %+v", warnings)
+	}
+}
+
+func TestMissingBoardReturnsActionWithoutProjectAndWarning(t *testing.T) {
+	ownedCard := trello.Card{ID: "an id", Name: "a name", BoardID: "missingBoardId"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddOwnedCard(&ownedCard)
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedActions := []Action{
+		{ID: "an id", Name: "a name"},
+	}
+	expectedWarnings := []Warning{
+		{Source: WarningSourceBoard, BoardID: "missingBoardId", Detail: "board with id missingBoardId not found"},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
 	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, expectedWarnings)
 }
 
 func TestEmptyTodoListDoesNotReturnAnAction(t *testing.T) {
@@ -292,8 +862,8 @@ func TestEmptyTodoListDoesNotReturnAnAction(t *testing.T) {
 	fakeClient.AddCardOnList("projectsListId", &projectCard)
 	fakeClient.AddListOnBoard("aBoardId", &todoList)
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err)
@@ -301,6 +871,7 @@ func TestEmptyTodoListDoesNotReturnAnAction(t *testing.T) {
 	if len(actions) != 0 {
 		t.Errorf("Unexpected number of actions returned, expected %d and got %d", 0, len(actions))
 	}
+	assertWarningsMatchExpected(t, warnings, []Warning{})
 }
 
 func TestFirstTodoListItemsAreReturnedAsActions(t *testing.T) {
@@ -313,8 +884,8 @@ func TestFirstTodoListItemsAreReturnedAsActions(t *testing.T) {
 	fakeClient.AddCardOnList("todoListId", &trello.Card{ID: "an id", Name: "a name", BoardID: "boardId"})
 	fakeClient.AddCardOnList("todoListId", &trello.Card{ID: "another id", Name: "another name", BoardID: "boardId"})
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
 	expectedActions := []Action{
 		{ID: "an id", Name: "a name", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
@@ -324,6 +895,251 @@ func TestFirstTodoListItemsAreReturnedAsActions(t *testing.T) {
 		t.Errorf("Unexpected error: %s", err)
 	}
 	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestConfiguredNumberOfTodoListItemsAreReturnedAsActions(t *testing.T) {
+	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/aBoardId"}
+	todoList := trello.List{ID: "todoListId", Name: "Todo"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+	fakeClient.AddListOnBoard("aBoardId", &todoList)
+	fakeClient.AddCardOnList("todoListId", &trello.Card{ID: "first id", Name: "first", BoardID: "boardId"})
+	fakeClient.AddCardOnList("todoListId", &trello.Card{ID: "second id", Name: "second", BoardID: "boardId"})
+	fakeClient.AddCardOnList("todoListId", &trello.Card{ID: "third id", Name: "third", BoardID: "boardId"})
+
+	cfg := testConfig()
+	cfg.TrelloActionsPerProject = 2
+
+	fetcher := Fetcher{Client: fakeClient, Config: cfg}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedActions := []Action{
+		{ID: "first id", Name: "first", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+		{ID: "second id", Name: "second", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestDefaultNumberOfTodoListItemsAreReturnedIfNotConfigured(t *testing.T) {
+	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/aBoardId"}
+	todoList := trello.List{ID: "todoListId", Name: "Todo"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+	fakeClient.AddListOnBoard("aBoardId", &todoList)
+	fakeClient.AddCardOnList("todoListId", &trello.Card{ID: "first id", Name: "first", BoardID: "boardId"})
+	fakeClient.AddCardOnList("todoListId", &trello.Card{ID: "second id", Name: "second", BoardID: "boardId"})
+
+	cfg := testConfig()
+	cfg.TrelloActionsPerProject = 0
+
+	fetcher := Fetcher{Client: fakeClient, Config: cfg}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedActions := []Action{
+		{ID: "first id", Name: "first", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestBlockedTodoListItemsAreSkippedIfConfigured(t *testing.T) {
+	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/aBoardId"}
+	todoList := trello.List{ID: "todoListId", Name: "Todo"}
+	waitingCard := trello.Card{
+		ID:      "waiting id",
+		Name:    "waiting",
+		BoardID: "boardId",
+		Labels:  []trello.Label{{Name: "waiting", Color: "yellow"}},
+	}
+	dependentCard := trello.Card{
+		ID:      "dependent id",
+		Name:    "dependent",
+		BoardID: "boardId",
+		Checklists: []trello.Checklist{
+			{Name: "Dependencies", CheckItems: []trello.CheckItem{{State: "complete"}, {State: "incomplete"}}},
+		},
+	}
+	readyCard := trello.Card{
+		ID:      "ready id",
+		Name:    "ready",
+		BoardID: "boardId",
+		Checklists: []trello.Checklist{
+			{Name: "Dependencies", CheckItems: []trello.CheckItem{{State: "complete"}}},
+			{Name: "Steps", CheckItems: []trello.CheckItem{{State: "incomplete"}}},
+		},
+	}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+	fakeClient.AddListOnBoard("aBoardId", &todoList)
+	fakeClient.AddCardOnList("todoListId", &waitingCard)
+	fakeClient.AddCardOnList("todoListId", &dependentCard)
+	fakeClient.AddCardOnList("todoListId", &readyCard)
+
+	cfg := testConfig()
+	cfg.TrelloSkipBlockedActions = true
+
+	fetcher := Fetcher{Client: fakeClient, Config: cfg}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedActions := []Action{
+		{ID: "ready id", Name: "ready", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestTodoListCanHaveAnyConfiguredName(t *testing.T) {
+	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/aBoardId"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "backlogListId", Name: "Backlog"})
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "toDoListId", Name: "to do"})
+	fakeClient.AddCardOnList("backlogListId", &trello.Card{ID: "backlog id", Name: "backlog", BoardID: "boardId"})
+	fakeClient.AddCardOnList("toDoListId", &trello.Card{ID: "todo id", Name: "todo", BoardID: "boardId"})
+
+	cfg := testConfig()
+	cfg.TrelloTodoListNames = []string{"Todo", "To Do", "Backlog"}
+
+	fetcher := Fetcher{Client: fakeClient, Config: cfg}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedActions := []Action{
+		{ID: "todo id", Name: "todo", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestProjectCardDescriptionOverridesTodoListName(t *testing.T) {
+	projectCard := trello.Card{
+		ID:          "an id",
+		Name:        "https://trello.com/b/aBoardId",
+		Description: "Some notes\n  TODO LIST: Next Steps  \nMore notes",
+	}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "todoListId", Name: "Todo"})
+	fakeClient.AddListOnBoard("aBoardId", &trello.List{ID: "nextStepsListId", Name: "Next Steps"})
+	fakeClient.AddCardOnList("todoListId", &trello.Card{ID: "todo id", Name: "todo", BoardID: "boardId"})
+	fakeClient.AddCardOnList("nextStepsListId", &trello.Card{ID: "next id", Name: "next", BoardID: "boardId"})
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedActions := []Action{
+		{ID: "next id", Name: "next", ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestMissingTodoListWithSeveralNamesReturnsWarning(t *testing.T) {
+	projectCard := trello.Card{ID: "an id", Name: "https://trello.com/b/empty"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+
+	cfg := testConfig()
+	cfg.TrelloTodoListNames = []string{"Todo", "To Do"}
+
+	fetcher := Fetcher{Client: fakeClient, Config: cfg}
+	_, warnings, err := fetcher.Fetch(context.Background())
+
+	expectedWarnings := []Warning{
+		{
+			Source:          WarningSourceProject,
+			ProjectCardID:   "an id",
+			ProjectCardName: "https://trello.com/b/empty",
+			BoardID:         "empty",
+			Detail:          "missing Todo list on board, expected a list named one of Todo, To Do",
+		},
+	}
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	assertWarningsMatchExpected(t, warnings, expectedWarnings)
+}
+
+func TestCardsFromSeveralSourcesAreReturnedOnceWithEverySource(t *testing.T) {
+	projectCard := trello.Card{ID: "project id", Name: "https://trello.com/b/aBoardId"}
+	todoList := trello.List{ID: "todoListId", Name: "Todo"}
+	sharedCard := trello.Card{ID: "shared id", Name: "shared", BoardID: "boardId"}
+	nextActionCard := trello.Card{ID: "next action id", Name: "next action", BoardID: "boardId"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddOwnedCard(&sharedCard)
+	fakeClient.AddCardOnList("nextActionsListId", &nextActionCard)
+	fakeClient.AddCardOnList("projectsListId", &projectCard)
+	fakeClient.AddListOnBoard("aBoardId", &todoList)
+	fakeClient.AddCardOnList("todoListId", &sharedCard)
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, _, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expectedSourcesByID := map[string][]string{
+		"shared id":      {ActionSourceOwned, ActionSourceProjectTodo},
+		"next action id": {ActionSourceNextActionsList},
+	}
+	if len(actions) != len(expectedSourcesByID) {
+		t.Fatalf("Expected %d actions, got %+v", len(expectedSourcesByID), actions)
+	}
+	for _, action := range actions {
+		if fmt.Sprint(action.Sources) != fmt.Sprint(expectedSourcesByID[action.ID]) {
+			t.Errorf("Expected action %s to have sources %v, got %v", action.ID, expectedSourcesByID[action.ID], action.Sources)
+		}
+	}
+}
+
+func TestCardsOnBoardLinkedFromTwoProjectsHaveTheirSourceOnce(t *testing.T) {
+	todoList := trello.List{ID: "todoListId", Name: "Todo"}
+	todoCard := trello.Card{ID: "todo id", Name: "todo", BoardID: "boardId"}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("projectsListId", &trello.Card{ID: "project id", Name: "https://trello.com/b/aBoardId"})
+	fakeClient.AddCardOnList("projectsListId", &trello.Card{ID: "other id", Name: "https://trello.com/b/aBoardId"})
+	fakeClient.AddListOnBoard("aBoardId", &todoList)
+	fakeClient.AddCardOnList("todoListId", &todoCard)
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, _, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(actions) != 1 || fmt.Sprint(actions[0].Sources) != fmt.Sprint([]string{ActionSourceProjectTodo}) {
+		t.Errorf("Expected one action with source %s once, got %+v", ActionSourceProjectTodo, actions)
+	}
 }
 
 func TestCardDueByDateIsAddedToActions(t *testing.T) {
@@ -333,8 +1149,8 @@ func TestCardDueByDateIsAddedToActions(t *testing.T) {
 	fakeClient := newFakeTrelloClient()
 	fakeClient.AddOwnedCard(&ownedCard)
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
 	expectedActions := []Action{
 		{ID: "an id", Name: "a name", DueBy: &dueBy, ImageURL: testImageURL("75x100"), ProjectName: "My Project"},
@@ -344,6 +1160,26 @@ func TestCardDueByDateIsAddedToActions(t *testing.T) {
 		t.Errorf("Unexpected error: %s", err)
 	}
 	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+}
+
+func TestCardLabelsAreAddedToActions(t *testing.T) {
+	labels := []trello.Label{{ID: "a label id", Name: "@office", Color: "green"}, {ID: "another label id", Name: "@phone"}}
+	ownedCard := trello.Card{ID: "an id", Name: "a name", BoardID: "boardId", Labels: labels}
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddOwnedCard(&ownedCard)
+
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, _, err := fetcher.Fetch(context.Background())
+
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	expectedLabels := []Label{{Name: "@office", Color: "green"}, {Name: "@phone"}}
+	if len(actions) != 1 || fmt.Sprint(actions[0].Labels) != fmt.Sprint(expectedLabels) {
+		t.Errorf("Expected one action with labels %+v, got %+v", expectedLabels, actions)
+	}
 }
 
 func TestBoardsWithNoBackgroundImagesCanStillReturnActions(t *testing.T) {
@@ -361,8 +1197,8 @@ func TestBoardsWithNoBackgroundImagesCanStillReturnActions(t *testing.T) {
 	fakeClient.AddOwnedCard(&ownedCard)
 	fakeClient.AddBoard(&boardWithNoBackgroundID)
 
-	fetcher := Fetcher{fakeClient, testConfig()}
-	actions, err := fetcher.Fetch()
+	fetcher := Fetcher{Client: fakeClient, Config: testConfig()}
+	actions, warnings, err := fetcher.Fetch(context.Background())
 
 	expectedActions := []Action{
 		{ID: "an id", Name: "a name", ProjectName: "My Project"},
@@ -372,6 +1208,7 @@ func TestBoardsWithNoBackgroundImagesCanStillReturnActions(t *testing.T) {
 		t.Errorf("Unexpected error: %s", err)
 	}
 	assertActionsMatchExpected(t, actions, expectedActions)
+	assertWarningsMatchExpected(t, warnings, []Warning{})
 }
 
 func assertActionsMatchExpected(t *testing.T, actions, expectedActions []Action) {
@@ -385,6 +1222,17 @@ func assertActionsMatchExpected(t *testing.T, actions, expectedActions []Action)
 	}
 }
 
+func assertWarningsMatchExpected(t *testing.T, warnings, expectedWarnings []Warning) {
+	if len(expectedWarnings) != len(warnings) {
+		t.Fatalf("Unexpected number of warnings returned, expected %d and got %d", len(expectedWarnings), len(warnings))
+	}
+	for i := range warnings {
+		if warnings[i] != expectedWarnings[i] {
+			t.Errorf("Expected warning %d to be %+v but got %+v", i, expectedWarnings[i], warnings[i])
+		}
+	}
+}
+
 func actionsAreEqual(action, other *Action) bool {
 	return (action.ID == other.ID &&
 		action.Name == other.Name &&
@@ -0,0 +1,121 @@
+package nextactions // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/config"
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+type directoryClient interface {
+	FetchDirectory(ctx context.Context) (*trello.Directory, error)
+}
+
+// listSetting is a config setting holding a list ID or reference
+type listSetting struct {
+	name  string
+	value *string
+}
+
+func listSettings(cfg *config.Config) []listSetting {
+	return []listSetting{
+		{"TRELLO_NEXT_ACTIONS_LIST_ID", &cfg.TrelloNextActionsListID},
+		{"TRELLO_PROJECTS_LIST_ID", &cfg.TrelloProjectsListID},
+		{"TRELLO_DONE_LIST_ID", &cfg.TrelloDoneListID},
+		{"TRELLO_WAITING_FOR_LIST_ID", &cfg.TrelloWaitingForListID},
+		{"TRELLO_INBOX_LIST_ID", &cfg.TrelloInboxListID},
+		{"TRELLO_SOMEDAY_LIST_ID", &cfg.TrelloSomedayListID},
+	}
+}
+
+// ListResolver looks up the IDs of lists that are configured as "board name/list name" references, so that boards
+// can be recreated, e.g. from a template, without changing the configuration
+type ListResolver struct {
+	Client directoryClient
+	Config *config.Config
+
+	mutex    sync.RWMutex
+	resolved *config.Config
+}
+
+// HasReferences returns true if any list is configured by name rather than by ID
+func (r *ListResolver) HasReferences() bool {
+	for _, setting := range listSettings(r.Config) {
+		if config.IsListReference(*setting.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve will look up the ID of every list that is configured by name. If any of them is missing or ambiguous, an
+// error listing every problem is returned and the lists found by any earlier call are kept.
+func (r *ListResolver) Resolve(ctx context.Context) error {
+	if !r.HasReferences() {
+		return nil
+	}
+
+	directory, err := r.Client.FetchDirectory(ctx)
+	if err != nil {
+		return fmt.Errorf("could not look up lists by name: %w", err)
+	}
+
+	resolved := *r.Config
+	problems := make([]string, 0)
+	for _, setting := range listSettings(&resolved) {
+		if !config.IsListReference(*setting.value) {
+			continue
+		}
+		list, err := directory.FindList(*setting.value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", setting.name, err))
+			continue
+		}
+		*setting.value = list.ID
+	}
+	if len(problems) > 0 {
+		return &config.ValidationError{Problems: problems}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.resolved = &resolved
+	return nil
+}
+
+// Current returns the config with the IDs of any lists configured by name. Until Resolve has succeeded, the config is
+// returned as it was given.
+func (r *ListResolver) Current() *config.Config {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if r.resolved == nil {
+		return r.Config
+	}
+	return r.resolved
+}
+
+// ResolvePeriodically will call Resolve at the specified interval until the context is done, so that lists which are
+// recreated or renamed are picked up while running. Errors are printed, and the lists found previously kept.
+func (r *ListResolver) ResolvePeriodically(ctx context.Context, interval time.Duration) {
+	if !r.HasReferences() {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Resolve(trello.WithoutCache(ctx)); err != nil {
+				fmt.Printf("Warning: %s\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package nextactions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/config"
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+func TestListResolverResolvesListsByName(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	cfg := &config.Config{
+		TrelloNextActionsListID: "inbox / next actions",
+		TrelloProjectsListID:    fake.cfg.TrelloProjectsListID,
+		TrelloDoneListID:        "My Project/Done",
+	}
+	resolver := ListResolver{Client: fake.Client(), Config: cfg}
+
+	if err := resolver.Resolve(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	resolved := resolver.Current()
+	if resolved.TrelloNextActionsListID != fake.cfg.TrelloNextActionsListID {
+		t.Errorf("Expected Next Actions list %s, got %s", fake.cfg.TrelloNextActionsListID, resolved.TrelloNextActionsListID)
+	}
+	if resolved.TrelloProjectsListID != fake.cfg.TrelloProjectsListID {
+		t.Errorf("Expected Projects list %s, got %s", fake.cfg.TrelloProjectsListID, resolved.TrelloProjectsListID)
+	}
+	if resolved.TrelloDoneListID != fake.doneListID {
+		t.Errorf("Expected Done list %s, got %s", fake.doneListID, resolved.TrelloDoneListID)
+	}
+	if cfg.TrelloNextActionsListID != "inbox / next actions" {
+		t.Errorf("Expected the original config to be left alone, got %+v", cfg)
+	}
+}
+
+func TestListResolverMakesNoRequestsWithoutReferences(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	resolver := ListResolver{Client: fake.Client(), Config: fake.cfg}
+
+	if err := resolver.Resolve(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if resolver.Current() != fake.cfg {
+		t.Errorf("Expected config to be returned unchanged, got %+v", resolver.Current())
+	}
+	if fake.RequestCount() != 0 {
+		t.Errorf("Expected no requests, got %q", fake.Requests())
+	}
+}
+
+func TestListResolverReportsMissingAndAmbiguousLists(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	duplicateBoardID := fake.AddBoard(trello.FakeBoard{Name: "My Project"})
+	fake.AddList(trello.FakeList{BoardID: duplicateBoardID, Name: "Done"})
+
+	cfg := &config.Config{
+		TrelloNextActionsListID: "Inbox/Missing",
+		TrelloProjectsListID:    fake.cfg.TrelloProjectsListID,
+		TrelloDoneListID:        "My Project/Done",
+	}
+	resolver := ListResolver{Client: fake.Client(), Config: cfg}
+
+	err := resolver.Resolve(context.Background())
+
+	var validationError *config.ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	expectedProblems := []string{
+		`TRELLO_NEXT_ACTIONS_LIST_ID: list reference "Inbox/Missing" does not match any list on the board`,
+		`TRELLO_DONE_LIST_ID: list reference "My Project/Done" is ambiguous, it matches 2 lists`,
+	}
+	if len(validationError.Problems) != len(expectedProblems) {
+		t.Fatalf("Expected problems %q, got %q", expectedProblems, validationError.Problems)
+	}
+	for i := range expectedProblems {
+		if validationError.Problems[i] != expectedProblems[i] {
+			t.Errorf("Expected problem %q, got %q", expectedProblems[i], validationError.Problems[i])
+		}
+	}
+}
+
+func TestListResolverKeepsPreviousListsIfResolvingFails(t *testing.T) {
+	fake := newFakeTrelloServer(t)
+
+	cfg := &config.Config{TrelloNextActionsListID: "Inbox/Next Actions", TrelloProjectsListID: "Inbox/Projects"}
+	resolver := ListResolver{Client: fake.Client(), Config: cfg}
+
+	if err := resolver.Resolve(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	fake.InjectErrors("/members/", nil, 404)
+	if err := resolver.Resolve(context.Background()); err == nil {
+		t.Error("Expected an error when Trello fails")
+	}
+
+	if resolver.Current().TrelloNextActionsListID != fake.cfg.TrelloNextActionsListID {
+		t.Errorf("Expected previously resolved config to be kept, got %+v", resolver.Current())
+	}
+}
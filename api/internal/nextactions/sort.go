@@ -0,0 +1,150 @@
+package nextactions // nolint:golint // package comment is in another file
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sort is a way of ordering actions
+type Sort string
+
+// SortSmart puts overdue actions first, then those due soon, both by due date, then the rest by their Trello position
+const SortSmart Sort = "smart"
+
+// SortDue orders actions by due date, with those without a due date last
+const SortDue Sort = "due"
+
+// SortProject orders actions by project name, then by their Trello position, with those without a project last
+const SortProject Sort = "project"
+
+// SortPosition orders actions by their Trello position
+const SortPosition Sort = "position"
+
+// DueSoonPeriod is how long before its due date an action is considered due soon by SortSmart
+const DueSoonPeriod = 24 * time.Hour
+
+// ParseSort parses the name of a Sort, returning SortSmart if the name is empty
+func ParseSort(value string) (Sort, error) {
+	switch s := Sort(strings.TrimSpace(value)); s {
+	case "":
+		return SortSmart, nil
+	case SortSmart, SortDue, SortProject, SortPosition:
+		return s, nil
+	default:
+		return "", fmt.Errorf(
+			"cannot sort by %q, expected one of %s, %s, %s or %s",
+			value,
+			SortSmart,
+			SortDue,
+			SortProject,
+			SortPosition,
+		)
+	}
+}
+
+// SortActions orders the actions in place, using SortSmart if the sort is not set. Actions that are otherwise equal
+// are ordered by ID, so that the order never changes between requests.
+func SortActions(actions []Action, s Sort, now time.Time) {
+	var compare func(a, b *Action) int
+	switch s {
+	case SortDue:
+		compare = compareDue
+	case SortProject:
+		compare = compareProject
+	case SortPosition:
+		compare = comparePosition
+	default:
+		compare = func(a, b *Action) int { return compareSmart(a, b, now) }
+	}
+
+	sort.Slice(actions, func(i, j int) bool {
+		if result := compare(&actions[i], &actions[j]); result != 0 {
+			return result < 0
+		}
+		return actions[i].ID < actions[j].ID
+	})
+}
+
+// compareSmart puts overdue actions first and then those due soon, both by due date, and then the rest by position
+func compareSmart(a, b *Action, now time.Time) int {
+	if result := compareInts(urgency(a, now), urgency(b, now)); result != 0 {
+		return result
+	}
+	if urgency(a, now) < notUrgent {
+		return compareDue(a, b)
+	}
+	return comparePosition(a, b)
+}
+
+const (
+	overdue = iota
+	dueSoon
+	notUrgent
+)
+
+func urgency(action *Action, now time.Time) int {
+	switch {
+	case action.DueBy == nil:
+		return notUrgent
+	case action.DueBy.Before(now):
+		return overdue
+	case action.DueBy.Before(now.Add(DueSoonPeriod)):
+		return dueSoon
+	default:
+		return notUrgent
+	}
+}
+
+func compareDue(a, b *Action) int {
+	switch {
+	case a.DueBy == nil && b.DueBy == nil:
+		return 0
+	case a.DueBy == nil:
+		return 1
+	case b.DueBy == nil:
+		return -1
+	case a.DueBy.Before(*b.DueBy):
+		return -1
+	case b.DueBy.Before(*a.DueBy):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareProject(a, b *Action) int {
+	switch {
+	case a.ProjectName == "" && b.ProjectName != "":
+		return 1
+	case a.ProjectName != "" && b.ProjectName == "":
+		return -1
+	}
+	if result := strings.Compare(strings.ToLower(a.ProjectName), strings.ToLower(b.ProjectName)); result != 0 {
+		return result
+	}
+	return comparePosition(a, b)
+}
+
+func comparePosition(a, b *Action) int {
+	switch {
+	case a.Position < b.Position:
+		return -1
+	case a.Position > b.Position:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
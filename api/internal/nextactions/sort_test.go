@@ -0,0 +1,58 @@
+package nextactions
+
+import (
+	"testing"
+	"time"
+)
+
+func sortTestActions(now time.Time) []Action {
+	overdue := now.Add(-48 * time.Hour)
+	lessOverdue := now.Add(-time.Hour)
+	dueSoon := now.Add(time.Hour)
+	dueLater := now.Add(7 * 24 * time.Hour)
+
+	return []Action{
+		{ID: "later", DueBy: &dueLater, ProjectName: "Beta", Position: 1},
+		{ID: "no due b", ProjectName: "alpha", Position: 3},
+		{ID: "soon", DueBy: &dueSoon, ProjectName: "Beta", Position: 4},
+		{ID: "no due a", Position: 3},
+		{ID: "less overdue", DueBy: &lessOverdue, ProjectName: "Alpha", Position: 5},
+		{ID: "overdue", DueBy: &overdue, ProjectName: "Beta", Position: 6},
+	}
+}
+
+func TestSortActions(t *testing.T) {
+	now := time.Date(2020, 2, 12, 16, 24, 0, 0, time.UTC)
+
+	testCases := map[Sort][]string{
+		SortSmart:    {"overdue", "less overdue", "soon", "later", "no due a", "no due b"},
+		"":           {"overdue", "less overdue", "soon", "later", "no due a", "no due b"},
+		SortDue:      {"overdue", "less overdue", "soon", "later", "no due a", "no due b"},
+		SortProject:  {"no due b", "less overdue", "later", "soon", "overdue", "no due a"},
+		SortPosition: {"later", "no due a", "no due b", "soon", "less overdue", "overdue"},
+	}
+	for s, expectedIDs := range testCases {
+		actions := sortTestActions(now)
+		SortActions(actions, s, now)
+
+		for i := range expectedIDs {
+			if actions[i].ID != expectedIDs[i] {
+				t.Errorf("Expected %q sort to return %q at %d, got %q", s, expectedIDs[i], i, actions[i].ID)
+			}
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	for value, expected := range map[string]Sort{"": SortSmart, "due": SortDue, " project ": SortProject} {
+		if s, err := ParseSort(value); err != nil || s != expected {
+			t.Errorf("Expected %q to be parsed as %q, got %q and error %v", value, expected, s, err)
+		}
+	}
+
+	_, err := ParseSort("name")
+	expectedError := `cannot sort by "name", expected one of smart, due, project or position`
+	if err == nil || err.Error() != expectedError {
+		t.Errorf("Expected error %q, got %v", expectedError, err)
+	}
+}
@@ -0,0 +1,99 @@
+package nextactions // nolint:golint // package comment is in another file
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/config"
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+// todoListMarker starts a line in a project card's description naming the project's Todo list, e.g.
+// "Todo list: Next Steps", for boards that don't follow the configured naming convention
+const todoListMarker = "todo list:"
+
+// todoListNames returns the names the Todo list of the project may have, in order of preference. A name given in the
+// project card's description is used instead of the configured names.
+func todoListNames(cfg *config.Config, projectCard *trello.Card) []string {
+	if name, ok := todoListNameFromDescription(projectCard.Description); ok {
+		return []string{name}
+	}
+	return cfg.TrelloTodoListNames
+}
+
+func todoListNameFromDescription(description string) (string, bool) {
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(strings.ToLower(line), todoListMarker) {
+			continue
+		}
+		if name := strings.TrimSpace(line[len(todoListMarker):]); name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// getTodoList returns the list with the first of the names that any of the lists has, ignoring case
+func getTodoList(lists []trello.List, names []string) (*trello.List, error) {
+	for _, name := range names {
+		for i := range lists {
+			if strings.EqualFold(strings.TrimSpace(lists[i].Name), name) {
+				return &lists[i], nil
+			}
+		}
+	}
+
+	if len(names) == 1 {
+		return nil, fmt.Errorf("missing %s list on board", names[0])
+	}
+	return nil, fmt.Errorf("missing Todo list on board, expected a list named one of %s", strings.Join(names, ", "))
+}
+
+// dependenciesChecklistName is the name of a checklist of things a card depends on, which block the card until they
+// have all been checked
+const dependenciesChecklistName = "Dependencies"
+
+// projectActionCards returns the first TrelloActionsPerProject cards on a project's Todo list, or the default number if
+// it isn't set, skipping any that are blocked if TrelloSkipBlockedActions is set
+func projectActionCards(cfg *config.Config, todoListCards []trello.Card) []trello.Card {
+	count := cfg.TrelloActionsPerProject
+	if count <= 0 {
+		count = config.DefaultTrelloActionsPerProject
+	}
+
+	cards := make([]trello.Card, 0, count)
+	for i := range todoListCards {
+		if len(cards) == count {
+			break
+		}
+		if cfg.TrelloSkipBlockedActions && isBlocked(&todoListCards[i], cfg.TrelloBlockedLabelNames) {
+			continue
+		}
+		cards = append(cards, todoListCards[i])
+	}
+	return cards
+}
+
+// isBlocked returns true if the card has one of the blocked labels, or a Dependencies checklist that isn't complete
+func isBlocked(card *trello.Card, blockedLabelNames []string) bool {
+	for _, label := range card.Labels {
+		for _, name := range blockedLabelNames {
+			if strings.EqualFold(strings.TrimSpace(label.Name), name) {
+				return true
+			}
+		}
+	}
+
+	for _, checklist := range card.Checklists {
+		if !strings.EqualFold(strings.TrimSpace(checklist.Name), dependenciesChecklistName) {
+			continue
+		}
+		for i := range checklist.CheckItems {
+			if !checklist.CheckItems[i].IsComplete() {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package nextactions // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// WaitingItem is a card on the Waiting For list, i.e. something that can't be done until someone else has done their
+// part
+type WaitingItem struct {
+	Action
+	// WaitingSince is when the card was last changed, which is taken to be when it started waiting. It is nil if
+	// Trello didn't say when that was, in which case WaitingDays is 0 and the item is never nudged.
+	WaitingSince *time.Time
+	WaitingDays  int
+	// Nudge is true if the item has been waiting for at least TrelloWaitingForNudgeDays
+	Nudge bool
+}
+
+// MarshalJSON returns a JSON representation of a WaitingItem
+func (w *WaitingItem) MarshalJSON() ([]byte, error) {
+	item := jsonWaitingItem{
+		jsonAction:   w.Action.toJSON("waitingItems"),
+		WaitingSince: w.WaitingSince,
+		Nudge:        w.Nudge,
+	}
+	if w.WaitingSince != nil {
+		item.WaitingDays = &w.WaitingDays
+	}
+	return json.Marshal(item)
+}
+
+// jsonWaitingItem leaves out waitingSince and waitingDays if it isn't known how long the item has been waiting
+type jsonWaitingItem struct {
+	jsonAction
+	WaitingSince *time.Time `json:"waitingSince,omitempty"`
+	WaitingDays  *int       `json:"waitingDays,omitempty"`
+	Nudge        bool       `json:"nudge"`
+}
+
+// FetchWaitingFor will fetch the cards on the Waiting For list, with those that have been waiting longest first and
+// any that it isn't known how long they have been waiting last. As with Fetch, problems with boards are returned as
+// warnings.
+func (f *Fetcher) FetchWaitingFor(ctx context.Context, now time.Time) ([]WaitingItem, []Warning, error) {
+	if f.Config.TrelloWaitingForListID == "" {
+		return nil, nil, &ListNotConfiguredError{ListName: "Waiting For"}
+	}
+
+	limitedFetcher, ctx := f.limit(ctx)
+	cards, err := limitedFetcher.fetchListCards(ctx, f.Config.TrelloWaitingForListID)
+	if err != nil {
+		return nil, nil, err
+	}
+	actions, warnings, err := limitedFetcher.listCardsToActions(ctx, cards, ActionSourceWaitingForList)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]WaitingItem, len(actions))
+	for i := range actions {
+		items[i] = WaitingItem{Action: actions[i]}
+		// A card without a last activity date would otherwise appear to have been waiting since the year 1
+		if cards[i].LastActivity.IsZero() {
+			continue
+		}
+		waitingSince := cards[i].LastActivity
+		waitingDays := int(now.Sub(waitingSince) / (24 * time.Hour))
+		items[i].WaitingSince = &waitingSince
+		items[i].WaitingDays = waitingDays
+		items[i].Nudge = f.Config.TrelloWaitingForNudgeDays > 0 && waitingDays >= f.Config.TrelloWaitingForNudgeDays
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return waitedLonger(&items[i], &items[j])
+	})
+	return items, warnings, nil
+}
+
+// waitedLonger returns true if the item has been waiting longer than the other one, treating items that it isn't known
+// how long they have been waiting as the most recent, and falling back to the ID so that the order is always the same
+func waitedLonger(item, other *WaitingItem) bool {
+	switch {
+	case item.WaitingSince == nil && other.WaitingSince == nil:
+		return item.ID < other.ID
+	case item.WaitingSince == nil || other.WaitingSince == nil:
+		return other.WaitingSince == nil
+	case !item.WaitingSince.Equal(*other.WaitingSince):
+		return item.WaitingSince.Before(*other.WaitingSince)
+	default:
+		return item.ID < other.ID
+	}
+}
@@ -0,0 +1,136 @@
+package nextactions
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+)
+
+func TestWaitingForItemsAreReturnedLongestWaitingFirst(t *testing.T) {
+	now := time.Date(2020, 2, 12, 16, 24, 0, 0, time.UTC)
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("waitingForListId", &trello.Card{
+		ID: "recent", Name: "Reply from Bob", BoardID: "boardId", LastActivity: now.Add(-36 * time.Hour),
+	})
+	fakeClient.AddCardOnList("waitingForListId", &trello.Card{
+		ID: "old", Name: "Parcel", BoardID: "boardId", LastActivity: now.Add(-10 * 24 * time.Hour),
+	})
+
+	cfg := testConfig()
+	cfg.TrelloWaitingForListID = "waitingForListId"
+	cfg.TrelloWaitingForNudgeDays = 7
+	fetcher := Fetcher{Client: fakeClient, Config: cfg}
+	items, warnings, err := fetcher.FetchWaitingFor(context.Background(), now)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assertWarningsMatchExpected(t, warnings, []Warning{})
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %+v", items)
+	}
+	if items[0].ID != "old" || items[0].WaitingDays != 10 || !items[0].Nudge || items[0].ProjectName != "My Project" {
+		t.Errorf("Expected old item waiting 10 days to be nudged, got %+v", items[0])
+	}
+	if items[1].ID != "recent" || items[1].WaitingDays != 1 || items[1].Nudge {
+		t.Errorf("Expected recent item waiting 1 day not to be nudged, got %+v", items[1])
+	}
+	if len(items[1].Sources) != 1 || items[1].Sources[0] != ActionSourceWaitingForList {
+		t.Errorf("Expected source %s, got %q", ActionSourceWaitingForList, items[1].Sources)
+	}
+}
+
+func TestWaitingForItemsAreNeverNudgedIfNotConfigured(t *testing.T) {
+	now := time.Date(2020, 2, 12, 16, 24, 0, 0, time.UTC)
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("waitingForListId", &trello.Card{
+		ID: "old", Name: "Parcel", BoardID: "boardId", LastActivity: now.Add(-100 * 24 * time.Hour),
+	})
+
+	cfg := testConfig()
+	cfg.TrelloWaitingForListID = "waitingForListId"
+	fetcher := Fetcher{Client: fakeClient, Config: cfg}
+	items, _, err := fetcher.FetchWaitingFor(context.Background(), now)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(items) != 1 || items[0].Nudge {
+		t.Errorf("Expected one item without a nudge, got %+v", items)
+	}
+}
+
+func TestFetchWaitingForRequiresWaitingForList(t *testing.T) {
+	fetcher := Fetcher{Client: newFakeTrelloClient(), Config: testConfig()}
+	_, _, err := fetcher.FetchWaitingFor(context.Background(), time.Now())
+
+	if err == nil || err.Error() != "no Waiting For list is configured" {
+		t.Errorf("Expected an error about the missing list, got %v", err)
+	}
+}
+
+func TestWaitingForItemsWithoutLastActivityAreNotNudged(t *testing.T) {
+	now := time.Date(2020, 2, 12, 16, 24, 0, 0, time.UTC)
+
+	fakeClient := newFakeTrelloClient()
+	fakeClient.AddCardOnList("waitingForListId", &trello.Card{ID: "unknown", Name: "Parcel", BoardID: "boardId"})
+	fakeClient.AddCardOnList("waitingForListId", &trello.Card{
+		ID: "recent", Name: "Reply from Bob", BoardID: "boardId", LastActivity: now.Add(-36 * time.Hour),
+	})
+
+	cfg := testConfig()
+	cfg.TrelloWaitingForListID = "waitingForListId"
+	cfg.TrelloWaitingForNudgeDays = 7
+	fetcher := Fetcher{Client: fakeClient, Config: cfg}
+	items, _, err := fetcher.FetchWaitingFor(context.Background(), now)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(items) != 2 || items[0].ID != "recent" {
+		t.Fatalf("Expected the item with a known wait first, got %+v", items)
+	}
+	if items[1].WaitingSince != nil || items[1].WaitingDays != 0 || items[1].Nudge {
+		t.Errorf("Expected item without last activity to have no wait and no nudge, got %+v", items[1])
+	}
+}
+
+func TestWaitingItemJSONWithoutWaitingSince(t *testing.T) {
+	item := WaitingItem{Action: Action{ID: "an id", Name: "a name", Sources: []string{}, Labels: []Label{}}}
+
+	data, err := json.Marshal(&item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedJSON := `{"type":"waitingItems","id":"an id","name":"a name","dueBy":null,"projectName":"",` +
+		`"sources":[],"labels":[],"url":"","imageUrl":null,"nudge":false}`
+	if string(data) != expectedJSON {
+		t.Errorf("Expected JSON %s, got %s", expectedJSON, data)
+	}
+}
+
+func TestWaitingItemJSON(t *testing.T) {
+	waitingSince := time.Date(2020, 2, 2, 16, 24, 0, 0, time.UTC)
+	item := WaitingItem{
+		Action:       Action{ID: "an id", Name: "a name", Sources: []string{}, Labels: []Label{}},
+		WaitingSince: &waitingSince,
+		WaitingDays:  10,
+		Nudge:        true,
+	}
+
+	data, err := json.Marshal(&item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedJSON := `{"type":"waitingItems","id":"an id","name":"a name","dueBy":null,"projectName":"",` +
+		`"sources":[],"labels":[],"url":"","imageUrl":null,` +
+		`"waitingSince":"2020-02-02T16:24:00Z","waitingDays":10,"nudge":true}`
+	if string(data) != expectedJSON {
+		t.Errorf("Expected JSON %s, got %s", expectedJSON, data)
+	}
+}
@@ -0,0 +1,40 @@
+package nextactions // nolint:golint // package comment is in another file
+
+import "github.com/stevecshanks/next-actions-in-go/api/internal/trello"
+
+// WarningSourceProject is the source of warnings caused by a project card that could not be resolved to a Todo list
+const WarningSourceProject = "project"
+
+// WarningSourceBoard is the source of warnings caused by a board that could not be fetched
+const WarningSourceBoard = "board"
+
+// WarningSourceInbox is the source of warnings caused by the Inbox list's cards not being counted
+const WarningSourceInbox = "inbox"
+
+// Warning describes a problem with a single source of Next Actions that did not prevent other actions from being
+// fetched
+type Warning struct {
+	Source          string `json:"source"`
+	ProjectCardID   string `json:"projectCardId,omitempty"`
+	ProjectCardName string `json:"projectCardName,omitempty"`
+	BoardID         string `json:"boardId,omitempty"`
+	Detail          string `json:"detail"`
+}
+
+func newProjectWarning(projectCard *trello.Card, boardID string, err error) *Warning {
+	return &Warning{
+		Source:          WarningSourceProject,
+		ProjectCardID:   projectCard.ID,
+		ProjectCardName: projectCard.Name,
+		BoardID:         boardID,
+		Detail:          err.Error(),
+	}
+}
+
+func newBoardWarning(boardID string, err error) *Warning {
+	return &Warning{
+		Source:  WarningSourceBoard,
+		BoardID: boardID,
+		Detail:  err.Error(),
+	}
+}
@@ -0,0 +1,239 @@
+package trello // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxBatchSize is the maximum number of requests Trello allows to be combined into a single batch request
+const MaxBatchSize = 10
+
+// BatchPath returns the path on the Trello API server where the specified paths can be requested in a single batch
+func BatchPath(relativePaths []string) string {
+	return "/batch?" + url.Values{"urls": {strings.Join(relativePaths, ",")}}.Encode()
+}
+
+// BatchResult is the result of a single request made as part of a batch request. Err will be set if Trello returned
+// an unsuccessful response for this request, otherwise Body will contain the response body.
+type BatchResult struct {
+	Path string
+	Body json.RawMessage
+	Err  error
+}
+
+// Batch will request each of the specified paths, using as few HTTP requests as possible. Results are returned in the
+// same order as the paths. An error is only returned if the batch as a whole failed, errors for individual paths are
+// returned in the relevant BatchResult.
+func (c *Client) Batch(ctx context.Context, relativePaths []string) ([]BatchResult, error) {
+	results := make([]BatchResult, len(relativePaths))
+
+	uncachedIndexes := make([]int, 0, len(relativePaths))
+	for i, relativePath := range relativePaths {
+		if body, ok := c.freshCachedBody(ctx, relativePath); ok {
+			results[i] = BatchResult{Path: relativePath, Body: body}
+		} else {
+			uncachedIndexes = append(uncachedIndexes, i)
+		}
+	}
+
+	for start := 0; start < len(uncachedIndexes); start += MaxBatchSize {
+		end := start + MaxBatchSize
+		if end > len(uncachedIndexes) {
+			end = len(uncachedIndexes)
+		}
+
+		chunkPaths := make([]string, 0, end-start)
+		for _, i := range uncachedIndexes[start:end] {
+			chunkPaths = append(chunkPaths, relativePaths[i])
+		}
+
+		chunkResults, err := c.batch(ctx, chunkPaths)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range uncachedIndexes[start:end] {
+			results[i] = chunkResults[j]
+			c.cacheBatchResult(chunkResults[j])
+		}
+	}
+
+	return results, nil
+}
+
+// freshCachedBody returns the cached response body for the path, if it can be used without revalidating it
+func (c *Client) freshCachedBody(ctx context.Context, relativePath string) ([]byte, bool) {
+	if c.Cache == nil || isCacheBypassed(ctx) {
+		return nil, false
+	}
+
+	entry, ok := c.Cache.Get(relativePath)
+	if !ok || !entry.IsFresh(time.Now()) {
+		return nil, false
+	}
+	return entry.Body, true
+}
+
+// cacheBatchResult stores a successful result so it can be reused by later requests. Trello doesn't return an ETag
+// for each result in a batch, so these entries can't be revalidated once they expire.
+func (c *Client) cacheBatchResult(result BatchResult) {
+	if c.Cache == nil || result.Err != nil {
+		return
+	}
+
+	c.Cache.Set(result.Path, &CacheEntry{
+		Body:      result.Body,
+		ExpiresAt: time.Now().Add(c.cacheTTLs().ttl(result.Path)),
+	})
+}
+
+// ListsOnBoards will return the lists on each of the specified boards, keyed by board ID. Boards that could not be
+// fetched are instead returned in the map of errors.
+func (c *Client) ListsOnBoards(ctx context.Context, boardIDs []string) (map[string][]List, map[string]error, error) {
+	listsByBoardID := make(map[string][]List)
+
+	errorsByBoardID, err := c.batchDecode(ctx, boardIDs, ListsOnBoardPath, func(boardID string, body []byte) error {
+		lists := make([]List, 0)
+		if err := json.Unmarshal(body, &lists); err != nil {
+			return err
+		}
+		listsByBoardID[boardID] = lists
+		return nil
+	})
+
+	return listsByBoardID, errorsByBoardID, err
+}
+
+// CardsOnLists will return the cards on each of the specified lists, keyed by list ID. Lists that could not be
+// fetched are instead returned in the map of errors.
+func (c *Client) CardsOnLists(ctx context.Context, listIDs []string) (map[string][]Card, map[string]error, error) {
+	return c.cardsOnLists(ctx, listIDs, CardsOnListPath)
+}
+
+// CardsWithChecklistsOnLists will return the cards on each of the specified lists along with their checklists, in the
+// same way as CardsOnLists
+func (c *Client) CardsWithChecklistsOnLists(
+	ctx context.Context,
+	listIDs []string,
+) (map[string][]Card, map[string]error, error) {
+	return c.cardsOnLists(ctx, listIDs, CardsWithChecklistsOnListPath)
+}
+
+func (c *Client) cardsOnLists(
+	ctx context.Context,
+	listIDs []string,
+	pathForListID func(listID string) string,
+) (map[string][]Card, map[string]error, error) {
+	cardsByListID := make(map[string][]Card)
+	pathForCardsOnList := func(listID string) string {
+		return cardsPath(ctx, pathForListID(listID))
+	}
+
+	errorsByListID, err := c.batchDecode(ctx, listIDs, pathForCardsOnList, func(listID string, body []byte) error {
+		cards := make([]Card, 0)
+		if err := json.Unmarshal(body, &cards); err != nil {
+			return err
+		}
+		cardsByListID[listID] = cards
+		return nil
+	})
+
+	return cardsByListID, errorsByListID, err
+}
+
+// GetBoards will return each of the specified boards, keyed by board ID. Boards that could not be fetched are instead
+// returned in the map of errors.
+func (c *Client) GetBoards(ctx context.Context, boardIDs []string) (map[string]*Board, map[string]error, error) {
+	boardsByID := make(map[string]*Board)
+
+	errorsByBoardID, err := c.batchDecode(ctx, boardIDs, BoardPath, func(boardID string, body []byte) error {
+		board := Board{}
+		if err := json.Unmarshal(body, &board); err != nil {
+			return err
+		}
+		boardsByID[boardID] = &board
+		return nil
+	})
+
+	return boardsByID, errorsByBoardID, err
+}
+
+func (c *Client) batchDecode(
+	ctx context.Context,
+	ids []string,
+	pathForID func(string) string,
+	decode func(id string, body []byte) error,
+) (map[string]error, error) {
+	relativePaths := make([]string, len(ids))
+	for i, id := range ids {
+		relativePaths[i] = pathForID(id)
+	}
+
+	results, err := c.Batch(ctx, relativePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	errorsByID := make(map[string]error)
+	for i, result := range results {
+		if result.Err == nil {
+			result.Err = decode(ids[i], result.Body)
+		}
+		if result.Err != nil {
+			errorsByID[ids[i]] = result.Err
+		}
+	}
+
+	return errorsByID, nil
+}
+
+func (c *Client) batch(ctx context.Context, relativePaths []string) ([]BatchResult, error) {
+	// The batch as a whole is never cached, since results for the individual paths are cached instead
+	body, err := c.getUncached(ctx, BatchPath(relativePaths))
+	if err != nil {
+		return nil, err
+	}
+
+	rawResults := make([]map[string]json.RawMessage, 0)
+	if err := json.Unmarshal(body, &rawResults); err != nil {
+		return nil, err
+	}
+	if len(rawResults) != len(relativePaths) {
+		return nil, fmt.Errorf("batch request returned %d results for %d paths", len(rawResults), len(relativePaths))
+	}
+
+	results := make([]BatchResult, len(relativePaths))
+	for i, rawResult := range rawResults {
+		results[i] = parseBatchResult(relativePaths[i], rawResult)
+	}
+
+	return results, nil
+}
+
+// parseBatchResult handles the formats Trello uses for each result in a batch response, either {"200": body} for a
+// successful request or an error object with a statusCode field for an unsuccessful one
+func parseBatchResult(relativePath string, rawResult map[string]json.RawMessage) BatchResult {
+	if body, ok := rawResult[strconv.Itoa(http.StatusOK)]; ok {
+		return BatchResult{Path: relativePath, Body: body}
+	}
+
+	statusCode := 0
+	if rawStatusCode, ok := rawResult["statusCode"]; ok {
+		_ = json.Unmarshal(rawStatusCode, &statusCode)
+	}
+	for key := range rawResult {
+		if code, err := strconv.Atoi(key); err == nil {
+			statusCode = code
+		}
+	}
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+
+	return BatchResult{Path: relativePath, Err: &APIError{StatusCode: statusCode, Path: relativePath}}
+}
@@ -8,6 +8,7 @@ import (
 // Board represents a Trello board returned via the API
 type Board struct {
 	ID          string      `json:"id"`
+	ShortLink   string      `json:"shortLink"`
 	Name        string      `json:"name"`
 	Preferences Preferences `json:"prefs"`
 }
@@ -0,0 +1,120 @@
+package trello // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores response bodies from the Trello API, keyed by the relative path they were requested from
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	// Expire marks every entry with a key starting with the prefix as stale, so it will be revalidated before reuse
+	Expire(keyPrefix string)
+}
+
+// CacheEntry is a single response body stored in a Cache
+type CacheEntry struct {
+	Body      []byte
+	ETag      string
+	ExpiresAt time.Time
+}
+
+// IsFresh returns true if the entry can be used without revalidating it with Trello
+func (e *CacheEntry) IsFresh(now time.Time) bool {
+	return now.Before(e.ExpiresAt)
+}
+
+// MemoryCache is a Cache that stores entries in memory, and is safe for concurrent use
+type MemoryCache struct {
+	mutex   sync.RWMutex
+	entries map[string]*CacheEntry
+}
+
+// NewMemoryCache creates an empty MemoryCache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]*CacheEntry)}
+}
+
+// Get returns the entry with the specified key, if there is one
+func (m *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	entry, ok := m.entries[key]
+	return entry, ok
+}
+
+// Set stores the entry with the specified key, replacing any existing entry
+func (m *MemoryCache) Set(key string, entry *CacheEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.entries[key] = entry
+}
+
+// Expire marks every entry with a key starting with the prefix as stale
+func (m *MemoryCache) Expire(keyPrefix string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for key, entry := range m.entries {
+		if strings.HasPrefix(key, keyPrefix) {
+			expiredEntry := *entry
+			expiredEntry.ExpiresAt = time.Time{}
+			m.entries[key] = &expiredEntry
+		}
+	}
+}
+
+// CacheTTLs controls how long each kind of resource is cached for before it needs to be revalidated with Trello
+type CacheTTLs struct {
+	Boards time.Duration
+	Lists  time.Duration
+	Cards  time.Duration
+}
+
+// DefaultCacheTTLs returns the TTLs used by a Client that has not been given any. Board names and backgrounds rarely
+// change, whereas cards are what we're actually interested in so should be kept up to date.
+func DefaultCacheTTLs() CacheTTLs {
+	return CacheTTLs{
+		Boards: time.Hour,
+		Lists:  5 * time.Minute,
+		Cards:  30 * time.Second,
+	}
+}
+
+// ttl returns how long the response from the specified path should be cached for
+func (t *CacheTTLs) ttl(relativePath string) time.Duration {
+	relativeURL, err := url.Parse(relativePath)
+	if err != nil {
+		return 0
+	}
+
+	switch {
+	case strings.HasSuffix(relativeURL.Path, "/cards") || strings.HasPrefix(relativeURL.Path, "/cards/"):
+		return t.Cards
+	case strings.HasSuffix(relativeURL.Path, "/lists") || strings.HasPrefix(relativeURL.Path, "/lists/"):
+		return t.Lists
+	case strings.HasPrefix(relativeURL.Path, "/boards/"):
+		return t.Boards
+	default:
+		return 0
+	}
+}
+
+type bypassCacheKey struct{}
+
+// WithoutCache returns a context that makes the Client ignore any cached responses, although the fresh responses will
+// still be cached for future requests
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+func isCacheBypassed(ctx context.Context) bool {
+	bypassed, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypassed
+}
@@ -28,11 +28,21 @@ func (u *urlWrapper) UnmarshalJSON(data []byte) error {
 
 // Card represents a Trello card returned via the API
 type Card struct {
-	ID      string     `json:"id"`
-	Name    string     `json:"name"`
-	DueBy   *time.Time `json:"due"`
-	URL     url.URL    `json:"-"`
-	BoardID string     `json:"idBoard"`
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	Description  string       `json:"desc"`
+	DueBy        *time.Time   `json:"due"`
+	DueComplete  bool         `json:"dueComplete"`
+	Closed       bool         `json:"closed"`
+	IsTemplate   bool         `json:"isTemplate"`
+	Position     float64      `json:"pos"`
+	LastActivity time.Time    `json:"dateLastActivity"`
+	URL          url.URL      `json:"-"`
+	BoardID      string       `json:"idBoard"`
+	ListID       string       `json:"idList"`
+	Labels       []Label      `json:"labels"`
+	Attachments  []Attachment `json:"attachments"`
+	Checklists   []Checklist  `json:"checklists"`
 }
 
 type cardAlias Card
@@ -57,3 +67,53 @@ func (jc *jsonCard) Card() Card {
 	card.URL = jc.URL.URL
 	return card
 }
+
+// Label represents a label on a Trello card
+type Label struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// Checklist represents a checklist on a Trello card
+type Checklist struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	CheckItems []CheckItem `json:"checkItems"`
+}
+
+// CheckItem represents an item on a Trello checklist
+type CheckItem struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// IsComplete returns true if the item has been checked
+func (i *CheckItem) IsComplete() bool {
+	return i.State == "complete"
+}
+
+// Attachment represents a file or link attached to a Trello card
+type Attachment struct {
+	ID   string  `json:"id"`
+	Name string  `json:"name"`
+	URL  url.URL `json:"-"`
+}
+
+type attachmentAlias Attachment
+
+// UnmarshalJSON converts JSON data into an Attachment
+func (a *Attachment) UnmarshalJSON(data []byte) error {
+	var jsonAttachment jsonAttachment
+	if err := json.Unmarshal(data, &jsonAttachment); err != nil {
+		return err
+	}
+	*a = Attachment(jsonAttachment.attachmentAlias)
+	a.URL = jsonAttachment.URL.URL
+	return nil
+}
+
+type jsonAttachment struct {
+	attachmentAlias
+	URL urlWrapper `json:"url"`
+}
@@ -0,0 +1,294 @@
+package trello // nolint:golint // package comment is in another file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RecordCassettesEnvironmentVariable switches clients created by NewCassetteClient into record mode, so that they make
+// requests and save the responses. When it is "true" the requests are made to Trello using TRELLO_KEY and
+// TRELLO_TOKEN, and when it is "sandbox" they are made to a sandbox board on a FakeServer instead.
+const RecordCassettesEnvironmentVariable = "TRELLO_RECORD_CASSETTES"
+
+const (
+	recordFromTrello  = "true"
+	recordFromSandbox = "sandbox"
+)
+
+// scrubbedValue replaces keys and tokens in recorded interactions
+const scrubbedValue = "SCRUBBED"
+
+// recordedHeaders are the only response headers worth keeping, since the client ignores the rest
+func recordedHeaders() []string {
+	return []string{"Content-Type", "ETag", "Retry-After"}
+}
+
+// Cassette is a sequence of HTTP requests to Trello and the responses they received, with keys and tokens removed
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and response. Body holds JSON responses as they were returned, whereas
+// Text holds anything else (such as error messages).
+type Interaction struct {
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	StatusCode int               `json:"statusCode"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       json.RawMessage   `json:"body,omitempty"`
+	Text       string            `json:"text,omitempty"`
+}
+
+// LoadCassette reads a cassette from a file
+func LoadCassette(filePath string) (*Cassette, error) {
+	contents, err := ioutil.ReadFile(filePath) // nolint:gosec // cassettes are chosen by tests, not users
+	if err != nil {
+		return nil, err
+	}
+
+	cassette := Cassette{}
+	if err := json.Unmarshal(contents, &cassette); err != nil {
+		return nil, fmt.Errorf("could not parse cassette %s: %w", filePath, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to a file, formatted so that changes are easy to review
+func (c *Cassette) Save(filePath string) error {
+	contents, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	contents = append(contents, "\n"...)
+
+	return ioutil.WriteFile(filePath, contents, 0644) // nolint:gosec // cassettes are committed, so not secret
+}
+
+// Recorder is an http.RoundTripper that passes requests on to another transport, and records them along with their
+// responses
+type Recorder struct {
+	Transport http.RoundTripper
+
+	key   string
+	token string
+
+	mutex    sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder creates a Recorder that will scrub the specified key and token from everything it records
+func NewRecorder(transport http.RoundTripper, key, token string) *Recorder {
+	return &Recorder{Transport: transport, key: key, token: token}
+}
+
+// RoundTrip makes the request using the underlying transport and records the result
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{
+		Method:     req.Method,
+		Path:       interactionPath(req.URL),
+		StatusCode: resp.StatusCode,
+		Header:     make(map[string]string),
+	}
+	for _, name := range recordedHeaders() {
+		if value := resp.Header.Get(name); value != "" {
+			interaction.Header[name] = r.scrub(value)
+		}
+	}
+	if scrubbedBody := r.scrub(string(body)); json.Valid([]byte(scrubbedBody)) {
+		interaction.Body = json.RawMessage(scrubbedBody)
+	} else {
+		interaction.Text = scrubbedBody
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+
+	return resp, nil
+}
+
+// Cassette returns everything recorded so far
+func (r *Recorder) Cassette() *Cassette {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return &Cassette{Interactions: append([]Interaction{}, r.cassette.Interactions...)}
+}
+
+func (r *Recorder) scrub(value string) string {
+	for _, secret := range []string{r.key, r.token} {
+		if secret != "" {
+			value = strings.ReplaceAll(value, secret, scrubbedValue)
+		}
+	}
+	return value
+}
+
+// Replayer is an http.RoundTripper that responds to requests using the interactions in a cassette, rather than
+// making real requests. Requests are matched by method and path, ignoring the key and token. If the same request
+// was recorded several times then the responses are replayed in order, with the last one repeated once they run out.
+type Replayer struct {
+	mutex        sync.Mutex
+	cassette     *Cassette
+	replayCounts map[int]int
+}
+
+// NewReplayer creates a Replayer for the cassette
+func NewReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{cassette: cassette, replayCounts: make(map[int]int)}
+}
+
+// RoundTrip responds with the recorded response to the request, or returns an error if there isn't one
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	interaction, ok := r.nextInteraction(req.Method, interactionPath(req.URL))
+	if !ok {
+		return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, interactionPath(req.URL))
+	}
+
+	body := []byte(interaction.Body)
+	if interaction.Body == nil {
+		body = []byte(interaction.Text)
+	}
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+		StatusCode:    interaction.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	for name, value := range interaction.Header {
+		resp.Header.Set(name, value)
+	}
+	return resp, nil
+}
+
+func (r *Replayer) nextInteraction(method, path string) (*Interaction, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	matchingIndexes := make([]int, 0)
+	for i, interaction := range r.cassette.Interactions {
+		if interaction.Method == method && interaction.Path == path {
+			matchingIndexes = append(matchingIndexes, i)
+		}
+	}
+	if len(matchingIndexes) == 0 {
+		return nil, false
+	}
+
+	for _, i := range matchingIndexes {
+		if r.replayCounts[i] == 0 {
+			r.replayCounts[i]++
+			return &r.cassette.Interactions[i], true
+		}
+	}
+	lastIndex := matchingIndexes[len(matchingIndexes)-1]
+	r.replayCounts[lastIndex]++
+	return &r.cassette.Interactions[lastIndex], true
+}
+
+// interactionPath returns the path and query of the URL without the key and token, with the query parameters in a
+// consistent order so that requests can be matched regardless of how they were built
+func interactionPath(requestURL *url.URL) string {
+	query := requestURL.Query()
+	query.Del("key")
+	query.Del("token")
+	if len(query) == 0 {
+		return requestURL.Path
+	}
+	return requestURL.Path + "?" + query.Encode()
+}
+
+// NewCassetteClient creates a Client for tests that replays the interactions in the cassette file. If record mode is
+// enabled by RecordCassettesEnvironmentVariable, the Client instead makes requests to Trello, or to the FakeServer
+// created by sandbox, and the cassette file is overwritten with them when the returned function is called. The
+// sandbox is only created when recording from it.
+func NewCassetteClient(
+	cassettePath string,
+	sandbox func() *FakeServer,
+	options ...ClientOption,
+) (*Client, func() error, error) {
+	switch os.Getenv(RecordCassettesEnvironmentVariable) {
+	case recordFromTrello:
+		key, token := os.Getenv("TRELLO_KEY"), os.Getenv("TRELLO_TOKEN")
+		if key == "" || token == "" {
+			return nil, nil, fmt.Errorf("TRELLO_KEY and TRELLO_TOKEN are required to record cassettes")
+		}
+		client, save := newRecordingClient(cassettePath, key, token, http.DefaultTransport, options...)
+		return client, save, nil
+	case recordFromSandbox:
+		return newSandboxRecordingClient(cassettePath, sandbox, options...)
+	}
+
+	cassette, err := LoadCassette(cassettePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Recorded retries are replayed in order, so there's no need to wait between them
+	replayOptions := []ClientOption{
+		WithHTTPClient(&http.Client{Transport: NewReplayer(cassette)}),
+		WithRetryPolicy(&RetryPolicy{MaxRetries: DefaultRetryPolicy().MaxRetries}),
+	}
+	client := NewClient(scrubbedValue, scrubbedValue, append(replayOptions, options...)...)
+
+	return client, func() error { return nil }, nil
+}
+
+func newSandboxRecordingClient(
+	cassettePath string,
+	sandbox func() *FakeServer,
+	options ...ClientOption,
+) (*Client, func() error, error) {
+	if sandbox == nil {
+		return nil, nil, fmt.Errorf("there is no sandbox to record %s from", cassettePath)
+	}
+
+	fake := sandbox()
+	sandboxOptions := append([]ClientOption{WithBaseURL(fake.URL())}, options...)
+	client, save := newRecordingClient(cassettePath, fake.Key, fake.Token, fake.server.Client().Transport,
+		sandboxOptions...)
+
+	return client, func() error {
+		defer fake.Close()
+		return save()
+	}, nil
+}
+
+func newRecordingClient(
+	cassettePath string,
+	key string,
+	token string,
+	transport http.RoundTripper,
+	options ...ClientOption,
+) (*Client, func() error) {
+	recorder := NewRecorder(transport, key, token)
+	recordOptions := []ClientOption{WithHTTPClient(&http.Client{Transport: recorder, Timeout: DefaultHTTPTimeout})}
+	client := NewClient(key, token, append(recordOptions, options...)...)
+
+	return client, func() error { return recorder.Cassette().Save(cassettePath) }
+}
@@ -0,0 +1,88 @@
+package trello // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Directory holds this user's open boards and the lists on each of them, so that lists can be found by name rather
+// than by ID
+type Directory struct {
+	Boards         []Board
+	ListsByBoardID map[string][]List
+}
+
+// FetchDirectory will fetch this user's open boards and the lists on each of them. Boards are sorted by name.
+func (c *Client) FetchDirectory(ctx context.Context) (*Directory, error) {
+	boards, err := c.OwnedBoards(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(boards, func(i, j int) bool {
+		return strings.ToLower(boards[i].Name) < strings.ToLower(boards[j].Name)
+	})
+
+	boardIDs := make([]string, len(boards))
+	for i, board := range boards {
+		boardIDs[i] = board.ID
+	}
+
+	listsByBoardID, errorsByBoardID, err := c.ListsOnBoards(ctx, boardIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, board := range boards {
+		if err := errorsByBoardID[board.ID]; err != nil {
+			return nil, fmt.Errorf("could not fetch lists on board %s: %w", board.Name, err)
+		}
+	}
+
+	return &Directory{Boards: boards, ListsByBoardID: listsByBoardID}, nil
+}
+
+// FindList returns the list referred to by a reference of the form "board name/list name". Names are matched
+// ignoring case and surrounding spaces, so "My Board / Todo" is also accepted. As board and list names may themselves
+// contain slashes, every possible split is tried, and an error is returned unless exactly one list matches.
+func (d *Directory) FindList(reference string) (*List, error) {
+	if !strings.Contains(reference, "/") {
+		return nil, fmt.Errorf(`list reference %q must be of the form "board name/list name"`, reference)
+	}
+
+	matchingLists := make([]List, 0)
+	matchedBoard := false
+	for i, char := range reference {
+		if char != '/' {
+			continue
+		}
+		boardName, listName := reference[:i], reference[i+1:]
+
+		for _, board := range d.Boards {
+			if !namesMatch(board.Name, boardName) {
+				continue
+			}
+			matchedBoard = true
+			for _, list := range d.ListsByBoardID[board.ID] {
+				if namesMatch(list.Name, listName) {
+					matchingLists = append(matchingLists, list)
+				}
+			}
+		}
+	}
+
+	switch {
+	case len(matchingLists) == 1:
+		return &matchingLists[0], nil
+	case len(matchingLists) > 1:
+		return nil, fmt.Errorf("list reference %q is ambiguous, it matches %d lists", reference, len(matchingLists))
+	case matchedBoard:
+		return nil, fmt.Errorf("list reference %q does not match any list on the board", reference)
+	default:
+		return nil, fmt.Errorf("list reference %q does not match any board", reference)
+	}
+}
+
+func namesMatch(name, reference string) bool {
+	return strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(reference))
+}
@@ -0,0 +1,63 @@
+package trello // nolint:golint // package comment is in another file
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIError is returned when the Trello API responds with an unsuccessful status code
+type APIError struct {
+	StatusCode int
+	Path       string
+	// RetryAfter is how long Trello asked us to wait before retrying, or zero if it did not say
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("request to %s returned status code %d", e.Path, e.StatusCode)
+}
+
+// IsNotFound returns true if the error was caused by Trello not finding the requested resource
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsRateLimited returns true if the error was caused by Trello rate limiting requests
+func IsRateLimited(err error) bool {
+	return hasStatusCode(err, http.StatusTooManyRequests)
+}
+
+// IsUnauthorized returns true if the error was caused by Trello rejecting the key or token
+func IsUnauthorized(err error) bool {
+	return hasStatusCode(err, http.StatusUnauthorized)
+}
+
+func hasStatusCode(err error, statusCode int) bool {
+	var apiError *APIError
+	return errors.As(err, &apiError) && apiError.StatusCode == statusCode
+}
+
+func newAPIError(response *http.Response, relativePath string) *APIError {
+	return &APIError{
+		StatusCode: response.StatusCode,
+		Path:       relativePath,
+		RetryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter handles both forms of the Retry-After header, i.e. a number of seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && time.Until(date) > 0 {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -0,0 +1,572 @@
+package trello // nolint:golint // package comment is in another file
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fakeTimeFormat is the format Trello uses for dates, which is always UTC with millisecond precision
+const fakeTimeFormat = "2006-01-02T15:04:05.000Z"
+
+const fakeNotFoundMessage = "The requested resource was not found."
+
+// fakeHandler responds to a request for a route, given the ID from the path (if it has one) and the query parameters.
+// The body is encoded as JSON for successful responses, or used as the error message otherwise.
+type fakeHandler func(id string, query url.Values) (int, interface{})
+
+type fakeBoardJSON struct {
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Closed    bool               `json:"closed"`
+	ShortLink string             `json:"shortLink"`
+	URL       string             `json:"url"`
+	ShortURL  string             `json:"shortUrl"`
+	Prefs     fakeBoardPrefsJSON `json:"prefs"`
+}
+
+type fakeBoardPrefsJSON struct {
+	BackgroundImageScaled []fakeImageJSON `json:"backgroundImageScaled"`
+}
+
+type fakeImageJSON struct {
+	URL string `json:"url"`
+}
+
+type fakeListJSON struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	Closed  bool    `json:"closed"`
+	IDBoard string  `json:"idBoard"`
+	Pos     float64 `json:"pos"`
+}
+
+type fakeCardJSON struct {
+	ID               string               `json:"id"`
+	Name             string               `json:"name"`
+	Desc             string               `json:"desc"`
+	Due              *string              `json:"due"`
+	DueComplete      bool                 `json:"dueComplete"`
+	Closed           bool                 `json:"closed"`
+	IsTemplate       bool                 `json:"isTemplate"`
+	IDBoard          string               `json:"idBoard"`
+	IDList           string               `json:"idList"`
+	IDMembers        []string             `json:"idMembers"`
+	IDLabels         []string             `json:"idLabels"`
+	Labels           []fakeLabelJSON      `json:"labels"`
+	IDChecklists     []string             `json:"idChecklists"`
+	Pos              float64              `json:"pos"`
+	ShortLink        string               `json:"shortLink"`
+	ShortURL         string               `json:"shortUrl"`
+	URL              string               `json:"url"`
+	DateLastActivity string               `json:"dateLastActivity"`
+	Attachments      []fakeAttachmentJSON `json:"attachments,omitempty"`
+	Checklists       []fakeChecklistJSON  `json:"checklists,omitempty"`
+}
+
+type fakeAttachmentJSON struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type fakeLabelJSON struct {
+	ID      string `json:"id"`
+	IDBoard string `json:"idBoard"`
+	Name    string `json:"name"`
+	Color   string `json:"color"`
+}
+
+type fakeChecklistJSON struct {
+	ID         string              `json:"id"`
+	IDCard     string              `json:"idCard"`
+	Name       string              `json:"name"`
+	CheckItems []fakeCheckItemJSON `json:"checkItems"`
+}
+
+type fakeCheckItemJSON struct {
+	Name  string  `json:"name"`
+	State string  `json:"state"`
+	Pos   float64 `json:"pos"`
+}
+
+func (f *FakeServer) routes() map[string]fakeHandler {
+	return map[string]fakeHandler{
+		"GET /members/*":          f.getMember,
+		"GET /members/*/cards":    f.getMemberCards,
+		"GET /members/*/boards":   f.getMemberBoards,
+		"GET /boards/*":           f.getBoard,
+		"GET /boards/*/lists":     f.getBoardLists,
+		"GET /boards/*/cards":     f.getBoardCards,
+		"GET /boards/*/labels":    f.getBoardLabels,
+		"GET /lists/*":            f.getList,
+		"GET /lists/*/cards":      f.getListCards,
+		"GET /cards/*":            f.getCard,
+		"GET /cards/*/checklists": f.getCardChecklists,
+		"PUT /cards/*":            f.putCard,
+		"POST /cards":             f.postCard,
+	}
+}
+
+// respond finds the handler for the request and calls it, or responds with 404 if there isn't one
+func (f *FakeServer) respond(method, relativePath string, query url.Values) (int, interface{}) {
+	parts := strings.Split(strings.Trim(relativePath, "/"), "/")
+	if method == http.MethodGet && len(parts) == 1 && parts[0] == "batch" {
+		return f.respondToBatch(query)
+	}
+
+	id := ""
+	if len(parts) > 1 {
+		id = parts[1]
+		parts[1] = "*"
+	}
+
+	handler, ok := f.routes()[method+" /"+strings.Join(parts, "/")]
+	if !ok {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return handler(id, query)
+}
+
+// respondToBatch responds to each of the requested URLs, in the same format as Trello
+func (f *FakeServer) respondToBatch(query url.Values) (int, interface{}) {
+	urls := strings.Split(query.Get("urls"), ",")
+	if len(urls) > MaxBatchSize {
+		return http.StatusBadRequest, fmt.Sprintf("batch is limited to %d URLs", MaxBatchSize)
+	}
+
+	results := make([]interface{}, 0, len(urls))
+	for _, rawURL := range urls {
+		relativeURL, err := url.Parse(rawURL)
+		if err != nil {
+			return http.StatusBadRequest, "invalid value for urls"
+		}
+
+		statusCode, body := f.respond(http.MethodGet, relativeURL.Path, relativeURL.Query())
+		if statusCode == http.StatusOK {
+			results = append(results, map[string]interface{}{strconv.Itoa(statusCode): body})
+		} else {
+			results = append(results, map[string]interface{}{
+				"name":       strings.ReplaceAll(http.StatusText(statusCode), " ", ""),
+				"message":    body,
+				"statusCode": statusCode,
+			})
+		}
+	}
+
+	return http.StatusOK, results
+}
+
+func (f *FakeServer) getMember(memberID string, _ url.Values) (int, interface{}) {
+	if memberID != "me" && memberID != f.MemberID {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+
+	return http.StatusOK, map[string]string{"id": f.MemberID, "username": f.Username, "fullName": f.FullName}
+}
+
+func (f *FakeServer) getMemberCards(memberID string, query url.Values) (int, interface{}) {
+	if memberID != "me" && memberID != f.MemberID {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+
+	return http.StatusOK, f.filteredCardsJSON(query, func(card *FakeCard) bool {
+		return containsString(card.MemberIDs, f.MemberID)
+	})
+}
+
+func (f *FakeServer) getMemberBoards(memberID string, _ url.Values) (int, interface{}) {
+	if memberID != "me" && memberID != f.MemberID {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+
+	boards := make([]fakeBoardJSON, 0, len(f.boards))
+	for _, board := range f.boards {
+		if !board.Closed {
+			boards = append(boards, board.json())
+		}
+	}
+	sort.Slice(boards, func(i, j int) bool { return boards[i].ID < boards[j].ID })
+
+	return http.StatusOK, boards
+}
+
+func (f *FakeServer) getBoard(boardID string, _ url.Values) (int, interface{}) {
+	board, ok := f.findBoard(boardID)
+	if !ok {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+	return http.StatusOK, board.json()
+}
+
+func (f *FakeServer) getBoardLists(boardID string, _ url.Values) (int, interface{}) {
+	board, ok := f.findBoard(boardID)
+	if !ok {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+
+	lists := make([]*FakeList, 0)
+	for _, list := range f.lists {
+		if list.BoardID == board.ID && !list.Closed {
+			lists = append(lists, list)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].Position < lists[j].Position || (lists[i].Position == lists[j].Position && lists[i].ID < lists[j].ID)
+	})
+
+	listsJSON := make([]fakeListJSON, len(lists))
+	for i, list := range lists {
+		listsJSON[i] = list.json()
+	}
+	return http.StatusOK, listsJSON
+}
+
+func (f *FakeServer) getBoardCards(boardID string, query url.Values) (int, interface{}) {
+	board, ok := f.findBoard(boardID)
+	if !ok {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+
+	return http.StatusOK, f.filteredCardsJSON(query, func(card *FakeCard) bool {
+		return card.BoardID == board.ID
+	})
+}
+
+func (f *FakeServer) getBoardLabels(boardID string, _ url.Values) (int, interface{}) {
+	board, ok := f.findBoard(boardID)
+	if !ok {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+
+	labels := make([]fakeLabelJSON, 0)
+	for _, label := range f.labels {
+		if label.BoardID == board.ID {
+			labels = append(labels, label.json())
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].ID < labels[j].ID })
+
+	return http.StatusOK, labels
+}
+
+func (f *FakeServer) getList(listID string, _ url.Values) (int, interface{}) {
+	list, ok := f.lists[listID]
+	if !ok {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+	return http.StatusOK, list.json()
+}
+
+func (f *FakeServer) getListCards(listID string, query url.Values) (int, interface{}) {
+	if _, ok := f.lists[listID]; !ok {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+
+	cardsJSON := f.filteredCardsJSON(query, func(card *FakeCard) bool {
+		return card.ListID == listID
+	})
+	for i := range cardsJSON {
+		if query.Get("attachments") == "true" {
+			cardsJSON[i].Attachments = f.attachmentsJSON(f.cards[cardsJSON[i].ID])
+		}
+		if query.Get("checklists") == "all" {
+			cardsJSON[i].Checklists = f.checklistsJSON(cardsJSON[i].ID)
+		}
+	}
+	return http.StatusOK, cardsJSON
+}
+
+func (f *FakeServer) attachmentsJSON(card *FakeCard) []fakeAttachmentJSON {
+	attachmentsJSON := make([]fakeAttachmentJSON, len(card.Attachments))
+	for i, attachment := range card.Attachments {
+		attachmentsJSON[i] = fakeAttachmentJSON{ID: attachment.ID, Name: attachment.Name, URL: attachment.URL}
+	}
+	return attachmentsJSON
+}
+
+func (f *FakeServer) getCard(cardID string, _ url.Values) (int, interface{}) {
+	card, ok := f.findCard(cardID)
+	if !ok {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+	return http.StatusOK, f.cardJSON(card)
+}
+
+func (f *FakeServer) getCardChecklists(cardID string, _ url.Values) (int, interface{}) {
+	card, ok := f.findCard(cardID)
+	if !ok {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+
+	return http.StatusOK, f.checklistsJSON(card.ID)
+}
+
+func (f *FakeServer) checklistsJSON(cardID string) []fakeChecklistJSON {
+	checklists := make([]fakeChecklistJSON, 0)
+	for _, checklist := range f.checklists {
+		if checklist.CardID == cardID {
+			checklists = append(checklists, checklist.json())
+		}
+	}
+	sort.Slice(checklists, func(i, j int) bool { return checklists[i].ID < checklists[j].ID })
+	return checklists
+}
+
+// putCard updates the card with any of the fields Trello allows to be set via query parameters
+func (f *FakeServer) putCard(cardID string, query url.Values) (int, interface{}) {
+	card, ok := f.findCard(cardID)
+	if !ok {
+		return http.StatusNotFound, fakeNotFoundMessage
+	}
+
+	updatedCard := *card
+	if err := f.updateCardFromQuery(&updatedCard, query); err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+	updatedCard.DateLastActivity = time.Now().UTC()
+	*card = updatedCard
+
+	return http.StatusOK, f.cardJSON(card)
+}
+
+// postCard creates a new card on the list specified by the idList query parameter
+func (f *FakeServer) postCard(_ string, query url.Values) (int, interface{}) {
+	list, ok := f.lists[query.Get("idList")]
+	if !ok {
+		return http.StatusBadRequest, "invalid value for idList"
+	}
+
+	card := FakeCard{ListID: list.ID}
+	if err := f.updateCardFromQuery(&card, query); err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+	f.storeNewCard(&card, list)
+
+	return http.StatusOK, f.cardJSON(&card)
+}
+
+func (f *FakeServer) updateCardFromQuery(card *FakeCard, query url.Values) error {
+	for name := range query {
+		value := query.Get(name)
+
+		var err error
+		switch name {
+		case "idList":
+			err = f.moveCardToList(card, value)
+		case "name":
+			card.Name = value
+		case "desc":
+			card.Description = value
+		case "closed":
+			card.Closed, err = strconv.ParseBool(value)
+		case "dueComplete":
+			card.DueComplete, err = strconv.ParseBool(value)
+		case "due":
+			card.Due, err = parseFakeDue(value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for %s", name)
+		}
+	}
+
+	// The position is relative to the other cards on the list, so can only be worked out once the card has moved
+	if position, ok := query["pos"]; ok {
+		var err error
+		if card.Position, err = f.cardPosition(card, position[0]); err != nil {
+			return fmt.Errorf("invalid value for pos")
+		}
+	}
+	return nil
+}
+
+// cardPosition converts a position of "top", "bottom" or a number into a position on the card's list
+func (f *FakeServer) cardPosition(card *FakeCard, position string) (float64, error) {
+	if position != "top" && position != "bottom" {
+		return strconv.ParseFloat(position, 64)
+	}
+
+	minPosition, maxPosition := 0.0, 0.0
+	for _, other := range f.cards {
+		if other.ListID != card.ListID || other.ID == card.ID {
+			continue
+		}
+		if minPosition == 0 || other.Position < minPosition {
+			minPosition = other.Position
+		}
+		if other.Position > maxPosition {
+			maxPosition = other.Position
+		}
+	}
+
+	if position == "top" {
+		if minPosition == 0 {
+			return fakePositionStep, nil
+		}
+		return minPosition / 2, nil
+	}
+	return maxPosition + fakePositionStep, nil
+}
+
+func (f *FakeServer) moveCardToList(card *FakeCard, listID string) error {
+	list, ok := f.lists[listID]
+	if !ok {
+		return fmt.Errorf("no list with ID %s", listID)
+	}
+	card.ListID = list.ID
+	card.BoardID = list.BoardID
+	return nil
+}
+
+func parseFakeDue(value string) (*time.Time, error) {
+	if value == "" || value == "null" {
+		return nil, nil
+	}
+	due, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &due, nil
+}
+
+// openCardsJSON returns the cards matching the filter that haven't been archived, in the order they appear on lists
+func (f *FakeServer) openCardsJSON(filter func(*FakeCard) bool) []fakeCardJSON {
+	return f.cardsJSON(func(card *FakeCard) bool {
+		return !card.Closed && filter(card)
+	})
+}
+
+// filteredCardsJSON is like openCardsJSON, but also supports Trello's "filter" query parameter, which can ask for all
+// cards or only the archived ones instead
+func (f *FakeServer) filteredCardsJSON(query url.Values, filter func(*FakeCard) bool) []fakeCardJSON {
+	switch query.Get("filter") {
+	case "all":
+		return f.cardsJSON(filter)
+	case "closed":
+		return f.cardsJSON(func(card *FakeCard) bool {
+			return card.Closed && filter(card)
+		})
+	default:
+		return f.openCardsJSON(filter)
+	}
+}
+
+// cardsJSON returns the cards matching the filter, in the order they appear on lists
+func (f *FakeServer) cardsJSON(filter func(*FakeCard) bool) []fakeCardJSON {
+	cards := make([]*FakeCard, 0)
+	for _, card := range f.cards {
+		if filter(card) {
+			cards = append(cards, card)
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].Position < cards[j].Position || (cards[i].Position == cards[j].Position && cards[i].ID < cards[j].ID)
+	})
+
+	cardsJSON := make([]fakeCardJSON, len(cards))
+	for i, card := range cards {
+		cardsJSON[i] = f.cardJSON(card)
+	}
+	return cardsJSON
+}
+
+func (f *FakeServer) cardJSON(card *FakeCard) fakeCardJSON {
+	cardJSON := fakeCardJSON{
+		ID:               card.ID,
+		Name:             card.Name,
+		Desc:             card.Description,
+		DueComplete:      card.DueComplete,
+		Closed:           card.Closed,
+		IsTemplate:       card.IsTemplate,
+		IDBoard:          card.BoardID,
+		IDList:           card.ListID,
+		IDMembers:        append([]string{}, card.MemberIDs...),
+		IDLabels:         append([]string{}, card.LabelIDs...),
+		Labels:           make([]fakeLabelJSON, 0),
+		IDChecklists:     make([]string, 0),
+		Pos:              card.Position,
+		ShortLink:        card.ShortLink,
+		ShortURL:         "https://trello.com/c/" + card.ShortLink,
+		URL:              "https://trello.com/c/" + card.ShortLink + "/" + fakeSlug(card.Name),
+		DateLastActivity: card.DateLastActivity.UTC().Format(fakeTimeFormat),
+	}
+	if card.Due != nil {
+		due := card.Due.UTC().Format(fakeTimeFormat)
+		cardJSON.Due = &due
+	}
+	for _, labelID := range card.LabelIDs {
+		if label, ok := f.labels[labelID]; ok {
+			cardJSON.Labels = append(cardJSON.Labels, label.json())
+		}
+	}
+	for _, checklist := range f.checklists {
+		if checklist.CardID == card.ID {
+			cardJSON.IDChecklists = append(cardJSON.IDChecklists, checklist.ID)
+		}
+	}
+	sort.Strings(cardJSON.IDChecklists)
+	return cardJSON
+}
+
+func (b *FakeBoard) json() fakeBoardJSON {
+	boardJSON := fakeBoardJSON{
+		ID:        b.ID,
+		Name:      b.Name,
+		Closed:    b.Closed,
+		ShortLink: b.ShortLink,
+		URL:       BoardBaseURL + b.ShortLink + "/" + fakeSlug(b.Name),
+		ShortURL:  BoardBaseURL + b.ShortLink,
+	}
+	for _, imageURL := range b.BackgroundImageURLs {
+		boardJSON.Prefs.BackgroundImageScaled = append(boardJSON.Prefs.BackgroundImageScaled, fakeImageJSON{imageURL})
+	}
+	return boardJSON
+}
+
+func (l *FakeList) json() fakeListJSON {
+	return fakeListJSON{ID: l.ID, Name: l.Name, Closed: l.Closed, IDBoard: l.BoardID, Pos: l.Position}
+}
+
+func (l *FakeLabel) json() fakeLabelJSON {
+	return fakeLabelJSON{ID: l.ID, IDBoard: l.BoardID, Name: l.Name, Color: l.Color}
+}
+
+func (c *FakeChecklist) json() fakeChecklistJSON {
+	checklistJSON := fakeChecklistJSON{ID: c.ID, IDCard: c.CardID, Name: c.Name, CheckItems: make([]fakeCheckItemJSON, 0)}
+	for i, item := range c.Items {
+		state := "incomplete"
+		if item.Complete {
+			state = "complete"
+		}
+		checklistJSON.CheckItems = append(checklistJSON.CheckItems, fakeCheckItemJSON{
+			Name:  item.Name,
+			State: state,
+			Pos:   float64(fakePositionStep * (i + 1)),
+		})
+	}
+	return checklistJSON
+}
+
+// fakeSlug converts a name into the form Trello uses at the end of board and card URLs
+func fakeSlug(name string) string {
+	nonAlphanumeric := regexp.MustCompile(`[^a-z0-9]+`)
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
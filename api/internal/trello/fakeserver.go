@@ -0,0 +1,412 @@
+package trello // nolint:golint // package comment is in another file
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakePositionStep is the gap Trello leaves between the positions of cards and lists added to the end of their parent
+const fakePositionStep = 16384
+
+// FakeServer is an in-memory stand-in for the Trello API, served over HTTP by an httptest.Server. It keeps state, so
+// changes made through a Client are visible to later requests, and it does not replace any global HTTP transport, so
+// tests using it can run in parallel. Like Trello, it sends an ETag with each response and responds with Not Modified
+// to requests whose If-None-Match header still matches.
+type FakeServer struct {
+	Key      string
+	Token    string
+	MemberID string
+	Username string
+	FullName string
+
+	server *httptest.Server
+
+	mutex      sync.Mutex
+	lastID     int
+	boards     map[string]*FakeBoard
+	lists      map[string]*FakeList
+	cards      map[string]*FakeCard
+	labels     map[string]*FakeLabel
+	checklists map[string]*FakeChecklist
+	latency    time.Duration
+	faults     []*fakeFault
+	requests   []string
+}
+
+// FakeBoard is a board stored by a FakeServer
+type FakeBoard struct {
+	ID                  string
+	ShortLink           string
+	Name                string
+	Closed              bool
+	BackgroundImageURLs []string
+}
+
+// FakeList is a list stored by a FakeServer
+type FakeList struct {
+	ID       string
+	BoardID  string
+	Name     string
+	Closed   bool
+	Position float64
+}
+
+// FakeCard is a card stored by a FakeServer. The card's board is always the board of the list it is on.
+type FakeCard struct {
+	ID               string
+	ShortLink        string
+	BoardID          string
+	ListID           string
+	Name             string
+	Description      string
+	Due              *time.Time
+	DueComplete      bool
+	Closed           bool
+	IsTemplate       bool
+	Position         float64
+	MemberIDs        []string
+	LabelIDs         []string
+	Attachments      []FakeAttachment
+	DateLastActivity time.Time
+}
+
+// FakeAttachment is a link attached to a FakeCard
+type FakeAttachment struct {
+	ID   string
+	Name string
+	URL  string
+}
+
+// FakeLabel is a label stored by a FakeServer
+type FakeLabel struct {
+	ID      string
+	BoardID string
+	Name    string
+	Color   string
+}
+
+// FakeChecklist is a checklist stored by a FakeServer
+type FakeChecklist struct {
+	ID     string
+	CardID string
+	Name   string
+	Items  []FakeCheckItem
+}
+
+// FakeCheckItem is an item on a FakeChecklist
+type FakeCheckItem struct {
+	Name     string
+	Complete bool
+}
+
+// fakeFault makes requests for paths with the specified prefix fail with each of the status codes in turn
+type fakeFault struct {
+	pathPrefix  string
+	statusCodes []int
+	header      http.Header
+}
+
+// NewFakeServer creates and starts a FakeServer that accepts the specified key and token. Close must be called once it
+// is no longer needed.
+func NewFakeServer(key, token string) *FakeServer {
+	fake := &FakeServer{
+		Key:        key,
+		Token:      token,
+		boards:     make(map[string]*FakeBoard),
+		lists:      make(map[string]*FakeList),
+		cards:      make(map[string]*FakeCard),
+		labels:     make(map[string]*FakeLabel),
+		checklists: make(map[string]*FakeChecklist),
+	}
+	fake.MemberID = fake.newID()
+	fake.Username = "fakeuser"
+	fake.FullName = "Fake User"
+	fake.server = httptest.NewServer(fake)
+	return fake
+}
+
+// Close shuts down the server
+func (f *FakeServer) Close() {
+	f.server.Close()
+}
+
+// URL returns the base URL of the fake API, for use with WithBaseURL
+func (f *FakeServer) URL() string {
+	return f.server.URL + "/1"
+}
+
+// Client returns a Client that sends requests to this server. Any options are applied after those pointing the Client
+// at the server.
+func (f *FakeServer) Client(options ...ClientOption) *Client {
+	serverOptions := []ClientOption{WithBaseURL(f.URL()), WithHTTPClient(f.server.Client())}
+	return NewClient(f.Key, f.Token, append(serverOptions, options...)...)
+}
+
+// AddBoard stores a board and returns its ID
+func (f *FakeServer) AddBoard(board FakeBoard) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	board.ID = f.newID()
+	board.ShortLink = f.newShortLink()
+	f.boards[board.ID] = &board
+	return board.ID
+}
+
+// AddList stores a list at the end of the board specified by the list's BoardID and returns its ID
+func (f *FakeServer) AddList(list FakeList) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, ok := f.boards[list.BoardID]; !ok {
+		panic(fmt.Sprintf("no board with ID %s", list.BoardID))
+	}
+
+	list.ID = f.newID()
+	if list.Position == 0 {
+		list.Position = float64(fakePositionStep * (len(f.lists) + 1))
+	}
+	f.lists[list.ID] = &list
+	return list.ID
+}
+
+// AddCard stores a card at the end of the list specified by the card's ListID and returns its ID
+func (f *FakeServer) AddCard(card FakeCard) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	list, ok := f.lists[card.ListID]
+	if !ok {
+		panic(fmt.Sprintf("no list with ID %s", card.ListID))
+	}
+
+	f.storeNewCard(&card, list)
+	return card.ID
+}
+
+// AddOwnedCard stores a card the fake user is a member of and returns its ID
+func (f *FakeServer) AddOwnedCard(card FakeCard) string {
+	card.MemberIDs = append(card.MemberIDs, f.MemberID)
+	return f.AddCard(card)
+}
+
+// AddLabel stores a label on the board specified by the label's BoardID and returns its ID
+func (f *FakeServer) AddLabel(label FakeLabel) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, ok := f.boards[label.BoardID]; !ok {
+		panic(fmt.Sprintf("no board with ID %s", label.BoardID))
+	}
+
+	label.ID = f.newID()
+	f.labels[label.ID] = &label
+	return label.ID
+}
+
+// AddChecklist stores a checklist on the card specified by the checklist's CardID and returns its ID
+func (f *FakeServer) AddChecklist(checklist FakeChecklist) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, ok := f.cards[checklist.CardID]; !ok {
+		panic(fmt.Sprintf("no card with ID %s", checklist.CardID))
+	}
+
+	checklist.ID = f.newID()
+	f.checklists[checklist.ID] = &checklist
+	return checklist.ID
+}
+
+// Board returns a copy of the board with the specified ID or short link, so that tests can check its state
+func (f *FakeServer) Board(idOrShortLink string) (FakeBoard, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	board, ok := f.findBoard(idOrShortLink)
+	if !ok {
+		return FakeBoard{}, false
+	}
+	return *board, true
+}
+
+// Card returns a copy of the card with the specified ID or short link, so that tests can check its state
+func (f *FakeServer) Card(idOrShortLink string) (FakeCard, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	card, ok := f.findCard(idOrShortLink)
+	if !ok {
+		return FakeCard{}, false
+	}
+	return *card, true
+}
+
+// SetLatency makes the server wait for the specified duration before responding to each request
+func (f *FakeServer) SetLatency(latency time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.latency = latency
+}
+
+// InjectErrors makes the next requests for paths starting with the prefix fail with each of the status codes in turn,
+// after which requests behave normally again. Any header is included in each of the error responses.
+func (f *FakeServer) InjectErrors(pathPrefix string, header http.Header, statusCodes ...int) {
+	if len(statusCodes) == 0 {
+		panic(fmt.Sprintf("no status codes to inject for %s", pathPrefix))
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.faults = append(f.faults, &fakeFault{pathPrefix: pathPrefix, statusCodes: statusCodes, header: header})
+}
+
+// Requests returns the method and path of every request made to the server so far, in the order they were made
+func (f *FakeServer) Requests() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return append([]string{}, f.requests...)
+}
+
+// RequestCount returns the number of requests made to the server so far
+func (f *FakeServer) RequestCount() int {
+	return len(f.Requests())
+}
+
+// ServeHTTP responds to a request in the same way as the Trello API
+func (f *FakeServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	relativePath := strings.TrimPrefix(req.URL.Path, "/1")
+
+	latency := f.recordRequest(req.Method + " " + relativePath)
+	select {
+	case <-time.After(latency):
+	case <-req.Context().Done():
+		return
+	}
+
+	query := req.URL.Query()
+	if query.Get("key") != f.Key || query.Get("token") != f.Token {
+		http.Error(w, "invalid key", http.StatusUnauthorized)
+		return
+	}
+
+	if statusCode, header, ok := f.nextFault(relativePath); ok {
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	statusCode, body := f.respond(req.Method, relativePath, query)
+	if statusCode != http.StatusOK {
+		http.Error(w, fmt.Sprint(body), statusCode)
+		return
+	}
+
+	encodedBody, err := json.Marshal(body)
+	if err != nil {
+		panic(err)
+	}
+	etag := fakeETag(encodedBody)
+	w.Header().Set("ETag", etag)
+	if req.Method == http.MethodGet && req.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(append(encodedBody, '\n')); err != nil {
+		panic(err)
+	}
+}
+
+// fakeETag returns an ETag that changes whenever the body does
+func fakeETag(body []byte) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write(body)
+	return fmt.Sprintf(`W/"%x"`, hash.Sum64())
+}
+
+func (f *FakeServer) recordRequest(request string) time.Duration {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.requests = append(f.requests, request)
+	return f.latency
+}
+
+func (f *FakeServer) nextFault(relativePath string) (int, http.Header, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for i, fault := range f.faults {
+		if !strings.HasPrefix(relativePath, fault.pathPrefix) {
+			continue
+		}
+
+		statusCode := fault.statusCodes[0]
+		fault.statusCodes = fault.statusCodes[1:]
+		if len(fault.statusCodes) == 0 {
+			f.faults = append(f.faults[:i], f.faults[i+1:]...)
+		}
+		return statusCode, fault.header, true
+	}
+	return 0, nil, false
+}
+
+func (f *FakeServer) storeNewCard(card *FakeCard, list *FakeList) {
+	card.ID = f.newID()
+	card.ShortLink = f.newShortLink()
+	card.BoardID = list.BoardID
+	if card.Position == 0 {
+		card.Position = float64(fakePositionStep * (len(f.cards) + 1))
+	}
+	if card.DateLastActivity.IsZero() {
+		card.DateLastActivity = time.Now().UTC()
+	}
+	card.Attachments = append([]FakeAttachment{}, card.Attachments...)
+	for i := range card.Attachments {
+		card.Attachments[i].ID = f.newID()
+	}
+	f.cards[card.ID] = card
+}
+
+// newID returns a unique ID in the same format as Trello's, which are 24 hex digits
+func (f *FakeServer) newID() string {
+	f.lastID++
+	return fmt.Sprintf("%024x", f.lastID)
+}
+
+// newShortLink returns a unique short link, which Trello uses in URLs and accepts in place of an ID
+func (f *FakeServer) newShortLink() string {
+	f.lastID++
+	return fmt.Sprintf("s%07x", f.lastID)
+}
+
+func (f *FakeServer) findBoard(idOrShortLink string) (*FakeBoard, bool) {
+	for _, board := range f.boards {
+		if board.ID == idOrShortLink || board.ShortLink == idOrShortLink {
+			return board, true
+		}
+	}
+	return nil, false
+}
+
+func (f *FakeServer) findCard(idOrShortLink string) (*FakeCard, bool) {
+	for _, card := range f.cards {
+		if card.ID == idOrShortLink || card.ShortLink == idOrShortLink {
+			return card, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,55 @@
+package trello // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// RequestLimiter limits how many requests to Trello are in flight at once, across every Client call made with a
+// context from WithRequestLimiter. A slot is only held while a request is being made, and not while a failed request
+// waits to be retried, so that backing off from rate limiting doesn't stop other requests from being made.
+type RequestLimiter struct {
+	semaphore chan struct{}
+}
+
+// NewRequestLimiter creates a RequestLimiter that allows up to the specified number of requests in flight at once
+func NewRequestLimiter(limit int) *RequestLimiter {
+	return &RequestLimiter{semaphore: make(chan struct{}, limit)}
+}
+
+type requestLimiterKey struct{}
+
+// WithRequestLimiter returns a context that makes the Client wait for a slot from the limiter before each request
+func WithRequestLimiter(ctx context.Context, limiter *RequestLimiter) context.Context {
+	return context.WithValue(ctx, requestLimiterKey{}, limiter)
+}
+
+// AcquireRequestSlot waits for a slot from the context's RequestLimiter, if it has one, and returns a function that
+// frees the slot again. Stand-ins for the Client can use it to honour the same limit.
+func AcquireRequestSlot(ctx context.Context) (func(), error) {
+	limiter, ok := ctx.Value(requestLimiterKey{}).(*RequestLimiter)
+	if !ok {
+		return func() {}, nil
+	}
+
+	select {
+	case limiter.semaphore <- struct{}{}:
+		var releaseOnce sync.Once
+		return func() { releaseOnce.Do(func() { <-limiter.semaphore }) }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// releasingBody frees a request's slot once its response body has been closed, since the request is still in flight
+// until then
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,8 @@
+package trello // nolint:golint // package comment is in another file
+
+// Member represents a Trello user returned via the API
+type Member struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	FullName string `json:"fullName"`
+}
@@ -0,0 +1,56 @@
+package trello // nolint:golint // package comment is in another file
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultHTTPTimeout is the default limit on how long a single HTTP request to Trello may take, including reading
+// the response body
+const DefaultHTTPTimeout = 30 * time.Second
+
+// ClientOption configures a Client created by NewClient
+type ClientOption func(*Client)
+
+// NewClient creates a Client that will use the specified key and token. Unless an HTTP client is provided, the Client
+// is given its own http.Client with a timeout, which shares the default transport's connection pool.
+func NewClient(key, token string, options ...ClientOption) *Client {
+	client := &Client{
+		Key:        key,
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: DefaultHTTPTimeout},
+	}
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// WithBaseURL makes the Client send requests to a server other than Trello, such as a local stand-in or a proxy
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.BaseURL = baseURL
+	}
+}
+
+// WithHTTPClient makes the Client send requests using the specified HTTP client
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithRetryPolicy makes the Client retry failed requests using the specified policy
+func WithRetryPolicy(retryPolicy *RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.RetryPolicy = retryPolicy
+	}
+}
+
+// WithCache makes the Client store responses in the specified cache, with the specified TTLs (or the defaults if nil)
+func WithCache(cache Cache, ttls *CacheTTLs) ClientOption {
+	return func(c *Client) {
+		c.Cache = cache
+		c.CacheTTLs = ttls
+	}
+}
@@ -0,0 +1,86 @@
+package trello // nolint:golint // package comment is in another file
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how failed requests to Trello are retried. Network errors and 5xx responses are retried with
+// exponential backoff and jitter, 429 responses are retried after the delay Trello asks for (falling back to
+// backoff), and any other unsuccessful response is never retried.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used by a Client that has not been given one
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  250 * time.Millisecond,
+		MaxDelay:   5 * time.Second,
+	}
+}
+
+// NoRetryPolicy returns a retry policy that never retries requests
+func NoRetryPolicy() RetryPolicy {
+	return RetryPolicy{}
+}
+
+// retryDelay returns how long to wait before retrying a request that failed with the specified error, and whether it
+// should be retried at all
+func (p *RetryPolicy) retryDelay(attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries {
+		return 0, false
+	}
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		// Anything other than an unsuccessful response is a network error, which is worth retrying
+		return p.backoff(attempt), true
+	}
+
+	switch {
+	case apiError.StatusCode == http.StatusTooManyRequests && apiError.RetryAfter > 0:
+		return apiError.RetryAfter, true
+	case apiError.StatusCode == http.StatusTooManyRequests || apiError.StatusCode >= 500:
+		return p.backoff(attempt), true
+	default:
+		return 0, false
+	}
+}
+
+// backoff returns an exponentially increasing delay for the attempt, with "equal jitter" applied so that concurrent
+// requests don't all retry at the same moment
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	halfDelay := delay / 2
+	return halfDelay + time.Duration(rand.Int63n(int64(halfDelay)+1)) // nolint:gosec // jitter need not be secure
+}
+
+// sleep waits for the specified duration, returning early with an error if the context is done first
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
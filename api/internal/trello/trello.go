@@ -2,11 +2,15 @@
 package trello
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
+	"time"
 )
 
 // APIBaseURL is the base URL for the Trello API
@@ -15,16 +19,58 @@ const APIBaseURL = "https://api.trello.com/1"
 // BoardBaseURL is the base URL for Trello boards
 const BoardBaseURL = "https://trello.com/b/"
 
+// CurrentMemberPath returns the path on the Trello API server where the user the token belongs to can be queried
+func CurrentMemberPath() string {
+	return "/members/me"
+}
+
+type archivedCardsKey struct{}
+
+// WithArchivedCards returns a context that makes the Client include archived cards when fetching the cards owned by
+// the user or on a list, which Trello leaves out by default
+func WithArchivedCards(ctx context.Context) context.Context {
+	return context.WithValue(ctx, archivedCardsKey{}, true)
+}
+
+// cardsPath adds the filter for archived cards to a path that lists cards, if the context asks for them
+func cardsPath(ctx context.Context, relativePath string) string {
+	if archived, _ := ctx.Value(archivedCardsKey{}).(bool); !archived {
+		return relativePath
+	}
+	if strings.Contains(relativePath, "?") {
+		return relativePath + "&filter=all"
+	}
+	return relativePath + "?filter=all"
+}
+
 // OwnedCardsPath returns the path on the Trello API server where a list of owned cards can be queried
 func OwnedCardsPath() string {
 	return "/members/me/cards"
 }
 
+// OwnedBoardsPath returns the path on the Trello API server where the open boards this user is a member of can be
+// queried
+func OwnedBoardsPath() string {
+	return "/members/me/boards?filter=open"
+}
+
 // CardsOnListPath returns the path on the Trello API server where cards on a list can be queried
 func CardsOnListPath(listID string) string {
 	return fmt.Sprintf("/lists/%s/cards", listID)
 }
 
+// CardsWithAttachmentsOnListPath returns the path on the Trello API server where cards on a list can be queried along
+// with their attachments
+func CardsWithAttachmentsOnListPath(listID string) string {
+	return CardsOnListPath(listID) + "?attachments=true&attachment_fields=name,url"
+}
+
+// CardsWithChecklistsOnListPath returns the path on the Trello API server where cards on a list can be queried along
+// with their checklists
+func CardsWithChecklistsOnListPath(listID string) string {
+	return CardsOnListPath(listID) + "?checklists=all"
+}
+
 // ListsOnBoardPath returns the path on the Trello API server where lists on a board can be queried
 func ListsOnBoardPath(boardID string) string {
 	return fmt.Sprintf("/boards/%s/lists", boardID)
@@ -35,108 +81,343 @@ func BoardPath(boardID string) string {
 	return fmt.Sprintf("/boards/%s", boardID)
 }
 
-// Client is used to interact with the Trello API
+// CardPath returns the path on the Trello API server where a card can be queried or updated
+func CardPath(cardID string) string {
+	return fmt.Sprintf("/cards/%s", cardID)
+}
+
+// Client is used to interact with the Trello API. NewClient should be used to create one with sensible defaults.
 type Client struct {
 	Key   string
 	Token string
+	// BaseURL is the URL of the Trello API server, APIBaseURL is used if this is empty
+	BaseURL string
+	// HTTPClient is used to send requests, http.DefaultClient is used if this is nil
+	HTTPClient *http.Client
+	// RetryPolicy controls how failed requests are retried, DefaultRetryPolicy is used if this is nil
+	RetryPolicy *RetryPolicy
+	// Cache stores responses so they don't need to be requested again, responses aren't cached if this is nil
+	Cache Cache
+	// CacheTTLs controls how long responses are cached for, DefaultCacheTTLs is used if this is nil
+	CacheTTLs *CacheTTLs
 }
 
 // OwnedCards will return the cards this user is a member of
-func (c *Client) OwnedCards() ([]Card, error) {
-	return c.getCards(OwnedCardsPath())
+func (c *Client) OwnedCards(ctx context.Context) ([]Card, error) {
+	return c.getCards(ctx, OwnedCardsPath())
+}
+
+// OwnedBoards will return the open boards this user is a member of
+func (c *Client) OwnedBoards(ctx context.Context) ([]Board, error) {
+	body, err := c.get(ctx, OwnedBoardsPath())
+	if err != nil {
+		return nil, err
+	}
+
+	boards := make([]Board, 0)
+	if err := json.Unmarshal(body, &boards); err != nil {
+		return nil, err
+	}
+
+	return boards, nil
 }
 
 // CardsOnList will return the cards on the specified list
-func (c *Client) CardsOnList(listID string) ([]Card, error) {
-	return c.getCards(CardsOnListPath(listID))
+func (c *Client) CardsOnList(ctx context.Context, listID string) ([]Card, error) {
+	return c.getCards(ctx, CardsOnListPath(listID))
+}
+
+// CardsWithAttachmentsOnList will return the cards on the specified list, including their attachments
+func (c *Client) CardsWithAttachmentsOnList(ctx context.Context, listID string) ([]Card, error) {
+	return c.getCards(ctx, CardsWithAttachmentsOnListPath(listID))
 }
 
 // ListsOnBoard will return the lists on the specified board
-func (c *Client) ListsOnBoard(boardID string) ([]List, error) {
-	return c.getLists(ListsOnBoardPath(boardID))
+func (c *Client) ListsOnBoard(ctx context.Context, boardID string) ([]List, error) {
+	return c.getLists(ctx, ListsOnBoardPath(boardID))
 }
 
 // GetBoard will return the board with the specified ID
-func (c *Client) GetBoard(boardID string) (*Board, error) {
-	return c.getBoard(BoardPath(boardID))
+func (c *Client) GetBoard(ctx context.Context, boardID string) (*Board, error) {
+	return c.getBoard(ctx, BoardPath(boardID))
+}
+
+// GetCard will return the card with the specified ID
+func (c *Client) GetCard(ctx context.Context, cardID string) (*Card, error) {
+	return c.getCard(ctx, CardPath(cardID))
 }
 
-func (c *Client) getCards(relativePath string) ([]Card, error) {
-	resp, err := c.get(relativePath)
+// CurrentMember will return the user the token belongs to, which is a cheap way to check the key and token are valid
+func (c *Client) CurrentMember(ctx context.Context) (*Member, error) {
+	body, err := c.get(ctx, CurrentMemberPath())
+	if err != nil {
+		return nil, err
+	}
+
+	member := Member{}
+	if err := json.Unmarshal(body, &member); err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// MarkCardDueComplete will mark the due date on the specified card as complete
+func (c *Client) MarkCardDueComplete(ctx context.Context, cardID string) error {
+	return c.updateCard(ctx, cardID, url.Values{"dueComplete": {"true"}})
+}
+
+// ArchiveCard will archive (close) the specified card
+func (c *Client) ArchiveCard(ctx context.Context, cardID string) error {
+	return c.updateCard(ctx, cardID, url.Values{"closed": {"true"}})
+}
+
+// MoveCardToList will move the specified card to the top of the specified list
+func (c *Client) MoveCardToList(ctx context.Context, cardID, listID string) error {
+	return c.updateCard(ctx, cardID, url.Values{"idList": {listID}, "pos": {"top"}})
+}
+
+func (c *Client) getCards(ctx context.Context, relativePath string) ([]Card, error) {
+	body, err := c.get(ctx, cardsPath(ctx, relativePath))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
 	cards := make([]Card, 0)
-	if err := json.NewDecoder(resp.Body).Decode(&cards); err != nil {
+	if err := json.Unmarshal(body, &cards); err != nil {
 		return nil, err
 	}
 
 	return cards, nil
 }
 
-func (c *Client) getLists(relativePath string) ([]List, error) {
-	resp, err := c.get(relativePath)
+func (c *Client) getLists(ctx context.Context, relativePath string) ([]List, error) {
+	body, err := c.get(ctx, relativePath)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
 	lists := make([]List, 0)
-	if err := json.NewDecoder(resp.Body).Decode(&lists); err != nil {
+	if err := json.Unmarshal(body, &lists); err != nil {
 		return nil, err
 	}
 
 	return lists, nil
 }
 
-func (c *Client) getBoard(relativePath string) (*Board, error) {
-	resp, err := c.get(relativePath)
+func (c *Client) getBoard(ctx context.Context, relativePath string) (*Board, error) {
+	body, err := c.get(ctx, relativePath)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
 	board := Board{}
-	if err := json.NewDecoder(resp.Body).Decode(&board); err != nil {
+	if err := json.Unmarshal(body, &board); err != nil {
 		return nil, err
 	}
 
 	return &board, nil
 }
 
-func (c *Client) get(relativePath string) (*http.Response, error) {
-	fmt.Printf("Making request to %s\n", relativePath)
-	client := &http.Client{}
+func (c *Client) getCard(ctx context.Context, relativePath string) (*Card, error) {
+	body, err := c.get(ctx, relativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	card := Card{}
+	if err := json.Unmarshal(body, &card); err != nil {
+		return nil, err
+	}
+
+	return &card, nil
+}
+
+func (c *Client) updateCard(ctx context.Context, cardID string, parameters url.Values) error {
+	relativeURL := url.URL{Path: CardPath(cardID), RawQuery: parameters.Encode()}
 
-	relativeURL, err := url.Parse(relativePath)
+	resp, err := c.do(ctx, "PUT", relativeURL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	// The card may have moved, or no longer be an action, so anything containing cards needs to be fetched again
+	if c.Cache != nil {
+		c.Cache.Expire(CardPath(cardID))
+		c.Cache.Expire(OwnedCardsPath())
+		c.Cache.Expire("/lists/")
+	}
+
+	return resp.Body.Close()
+}
+
+// get returns the response body from the specified path, from the cache if possible
+func (c *Client) get(ctx context.Context, relativePath string) ([]byte, error) {
+	if c.Cache == nil {
+		return c.getUncached(ctx, relativePath)
+	}
+
+	entry, isCached := c.Cache.Get(relativePath)
+	if isCached && entry.IsFresh(time.Now()) && !isCacheBypassed(ctx) {
+		return entry.Body, nil
+	}
+
+	// Even if the entry is stale, Trello can tell us it hasn't changed rather than sending it all again
+	header := http.Header{}
+	if isCached && entry.ETag != "" {
+		header.Set("If-None-Match", entry.ETag)
+	}
+
+	resp, err := c.do(ctx, "GET", relativePath, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	newEntry := CacheEntry{ETag: resp.Header.Get("ETag"), ExpiresAt: time.Now().Add(c.cacheTTLs().ttl(relativePath))}
+	switch {
+	case resp.StatusCode == http.StatusNotModified && isCached:
+		newEntry.Body = entry.Body
+		newEntry.ETag = entry.ETag
+	case resp.StatusCode == http.StatusNotModified:
+		// There's nothing to reuse if the response wasn't cached, e.g. because it was evicted in the meantime
+		return nil, newAPIError(resp, relativePath)
+	default:
+		if newEntry.Body, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	c.Cache.Set(relativePath, &newEntry)
+
+	return newEntry.Body, nil
+}
+
+func (c *Client) getUncached(ctx context.Context, relativePath string) ([]byte, error) {
+	resp, err := c.do(ctx, "GET", relativePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (c *Client) cacheTTLs() *CacheTTLs {
+	if c.CacheTTLs == nil {
+		defaultCacheTTLs := DefaultCacheTTLs()
+		return &defaultCacheTTLs
+	}
+	return c.CacheTTLs
+}
+
+func (c *Client) do(ctx context.Context, method, relativePath string, header http.Header) (*http.Response, error) {
+	fullURL, err := c.fullURL(relativePath)
 	if err != nil {
 		return nil, err
 	}
 
+	retryPolicy := c.retryPolicy()
+
+	for attempt := 0; ; attempt++ {
+		response, err := c.doOnce(ctx, method, relativePath, fullURL, header)
+		if err == nil {
+			return response, nil
+		}
+
+		delay, shouldRetry := retryPolicy.retryDelay(attempt, err)
+		if !shouldRetry || ctx.Err() != nil {
+			return nil, err
+		}
+
+		fmt.Printf("Retrying %s request to %s in %s after error: %s\n", method, relativePath, delay, err)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) doOnce(
+	ctx context.Context,
+	method, relativePath, fullURL string,
+	header http.Header,
+) (*http.Response, error) {
+	// Don't bother starting a request if the caller has already given up on it
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	release, err := AcquireRequestSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Making %s request to %s\n", method, relativePath)
+
+	response, err := c.httpClient().Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// Not Modified is only returned for conditional requests, where it is the response we're hoping for
+	if response.StatusCode >= 300 && response.StatusCode != http.StatusNotModified {
+		response.Body.Close()
+		release()
+		return nil, newAPIError(response, relativePath)
+	}
+
+	response.Body = &releasingBody{ReadCloser: response.Body, release: release}
+	return response, nil
+}
+
+func (c *Client) fullURL(relativePath string) (string, error) {
+	relativeURL, err := url.Parse(relativePath)
+	if err != nil {
+		return "", err
+	}
+
 	queryParameters := relativeURL.Query()
 	queryParameters.Add("key", c.Key)
 	queryParameters.Add("token", c.Token)
 
-	baseURL, _ := url.Parse(APIBaseURL)
+	baseURL, err := url.Parse(c.baseURL())
+	if err != nil {
+		return "", err
+	}
 	fullURL := baseURL.ResolveReference(&url.URL{
 		Path:     path.Join(baseURL.Path, relativeURL.Path),
 		RawQuery: queryParameters.Encode(),
 	})
 
-	req, err := http.NewRequest("GET", fullURL.String(), nil)
-	if err != nil {
-		return nil, err
-	}
+	return fullURL.String(), nil
+}
 
-	response, err := client.Do(req)
-	if err != nil {
-		return nil, err
+func (c *Client) baseURL() string {
+	if c.BaseURL == "" {
+		return APIBaseURL
 	}
-	if response.StatusCode >= 300 {
-		return nil, fmt.Errorf("request to %s returned status code %d", relativePath, response.StatusCode)
+	return c.BaseURL
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
 	}
+	return c.HTTPClient
+}
 
-	return response, nil
+func (c *Client) retryPolicy() *RetryPolicy {
+	if c.RetryPolicy == nil {
+		defaultRetryPolicy := DefaultRetryPolicy()
+		return &defaultRetryPolicy
+	}
+	return c.RetryPolicy
 }
@@ -1,153 +1,988 @@
 package trello
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 )
 
-func TestClientOwnedCardsReturnsExpectedResponse(t *testing.T) {
-	mockServer := CreateMockServer("some key", "some token")
-	defer TeardownMockServer()
+func TestClientBatchSplitsRequestsIntoChunks(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
 
-	mockServer.AddFileResponse(OwnedCardsPath(), "./testdata/my_cards_response.json")
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
 
-	client := Client{"some key", "some token"}
+	relativePaths := make([]string, 0)
+	for i := 0; i < MaxBatchSize+5; i++ {
+		relativePaths = append(relativePaths, BoardPath(fmt.Sprintf("missingBoard%d", i)))
+	}
+	relativePaths[12] = BoardPath(boardID)
 
-	cards, err := client.OwnedCards()
+	results, err := fake.Client().Batch(context.Background(), relativePaths)
 	if err != nil {
-		t.Errorf("OwnedCards returned error: %s", err)
+		t.Fatalf("Batch returned error: %s", err)
+	}
+	if len(results) != len(relativePaths) {
+		t.Fatalf("Batch returned %d results, expected %d", len(results), len(relativePaths))
+	}
+	for i, result := range results {
+		if result.Path != relativePaths[i] {
+			t.Errorf("Expected result %d to have path %s, got %s", i, relativePaths[i], result.Path)
+		}
+		if (i == 12) != (result.Err == nil) {
+			t.Errorf("Unexpected error for result %d: %s", i, result.Err)
+		}
+	}
+	if fake.RequestCount() != 2 {
+		t.Errorf("Expected %d requests, got %d", 2, fake.RequestCount())
+	}
+}
+
+func TestClientHandlesHTTPErrors(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	_, err := fake.Client().GetBoard(context.Background(), "missingBoardId")
+	if err == nil {
+		t.Fatal("Client did not return 404 error")
 	}
 
-	expectedDueBy, _ := time.Parse(time.RFC3339, "2020-01-01T10:30:00.000Z")
-	expectedURL1, _ := url.Parse("https://trello.com/c/abcd1234/10-my-first-card")
-	expectedCard1 := Card{
-		ID:      "myFirstCardId",
-		Name:    "My First Action",
-		DueBy:   &expectedDueBy,
-		URL:     *expectedURL1,
-		BoardID: "myBoardId",
+	expectedError := fmt.Errorf("request to %s returned status code %d", BoardPath("missingBoardId"), 404)
+	if err.Error() != expectedError.Error() {
+		t.Errorf("Expected error %s, got %s", expectedError, err)
 	}
-	expectedURL2, _ := url.Parse("https://trello.com/c/bcde2345/11-my-second-card")
-	expectedCard2 := Card{
-		ID:      "mySecondCardId",
-		Name:    "My Second Action",
-		URL:     *expectedURL2,
-		BoardID: "myBoardId",
+	if !IsNotFound(err) || IsRateLimited(err) || IsUnauthorized(err) {
+		t.Errorf("Expected a not found error, got %s", err)
 	}
+}
 
-	assertCardsMatchExpected(t, cards, []Card{expectedCard1, expectedCard2})
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+	fake.InjectErrors(BoardPath(boardID), nil, http.StatusUnauthorized)
+
+	client := fake.Client(WithRetryPolicy(testRetryPolicy()))
+
+	_, err := client.GetBoard(context.Background(), boardID)
+	if !IsUnauthorized(err) {
+		t.Errorf("Expected an unauthorized error, got %s", err)
+	}
+	if fake.RequestCount() != 1 {
+		t.Errorf("Expected %d request, got %d", 1, fake.RequestCount())
+	}
 }
 
-func TestClientCardsOnList(t *testing.T) {
-	mockServer := CreateMockServer("some key", "some token")
-	defer TeardownMockServer()
+func TestClientRetriesServerErrorsAndRateLimiting(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
 
-	mockServer.AddFileResponse(CardsOnListPath("123"), "./testdata/next_actions_list_response.json")
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+	fake.InjectErrors(BoardPath(boardID), nil, http.StatusServiceUnavailable, http.StatusTooManyRequests)
 
-	client := Client{"some key", "some token"}
+	client := fake.Client(WithRetryPolicy(testRetryPolicy()))
 
-	cards, err := client.CardsOnList("123")
+	board, err := client.GetBoard(context.Background(), boardID)
 	if err != nil {
-		t.Errorf("CardsOnList returned error: %s", err)
+		t.Fatalf("GetBoard returned error: %s", err)
 	}
+	if board.ID != boardID {
+		t.Errorf("GetBoard returned incorrect board %+v", board)
+	}
+	if fake.RequestCount() != 3 {
+		t.Errorf("Expected %d requests, got %d", 3, fake.RequestCount())
+	}
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+	tooManyRequests := []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	fake.InjectErrors(BoardPath(boardID), http.Header{"Retry-After": {"0"}}, tooManyRequests...)
 
-	expectedDueBy, _ := time.Parse(time.RFC3339, "2020-01-15T10:29:59.000Z")
-	expectedURL, _ := url.Parse("https://trello.com/c/cdef3456/33-my-third-card")
-	expectedCard1 := Card{
-		ID:      "todoCardId",
-		Name:    "Todo Action",
-		DueBy:   &expectedDueBy,
-		URL:     *expectedURL,
-		BoardID: "myBoardId",
+	client := fake.Client(WithRetryPolicy(testRetryPolicy()))
+
+	_, err := client.GetBoard(context.Background(), boardID)
+	if !IsRateLimited(err) {
+		t.Errorf("Expected a rate limited error, got %s", err)
+	}
+	if fake.RequestCount() != 3 {
+		t.Errorf("Expected %d requests, got %d", 3, fake.RequestCount())
 	}
+}
 
-	assertCardsMatchExpected(t, cards, []Card{expectedCard1})
+func TestRetryPolicyHonoursRetryAfter(t *testing.T) {
+	retryPolicy := RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Second}
+	err := &APIError{StatusCode: 429, Path: "/", RetryAfter: parseRetryAfter("120")}
+
+	delay, shouldRetry := retryPolicy.retryDelay(0, err)
+	if !shouldRetry || delay != 2*time.Minute {
+		t.Errorf("Expected retry after %s, got %s (retry: %t)", 2*time.Minute, delay, shouldRetry)
+	}
+
+	if _, shouldRetry := retryPolicy.retryDelay(1, err); shouldRetry {
+		t.Error("Expected no retry after MaxRetries attempts")
+	}
 }
 
-func TestClientListsOnBoard(t *testing.T) {
-	mockServer := CreateMockServer("some key", "some token")
-	defer TeardownMockServer()
+func TestRetryPolicyBacksOffExponentially(t *testing.T) {
+	retryPolicy := RetryPolicy{MaxRetries: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	err := &APIError{StatusCode: 502, Path: "/"}
+
+	expectedMaxDelays := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for attempt, expectedMaxDelay := range expectedMaxDelays {
+		delay, shouldRetry := retryPolicy.retryDelay(attempt, err)
+		if !shouldRetry || delay < expectedMaxDelay/2 || delay > expectedMaxDelay {
+			t.Errorf("Expected delay between %s and %s, got %s", expectedMaxDelay/2, expectedMaxDelay, delay)
+		}
+	}
+}
+
+func testRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+}
 
-	mockServer.AddFileResponse(ListsOnBoardPath("789"), "./testdata/board_lists_response.json")
+func TestClientStopsWhenContextIsCancelled(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := fake.Client().GetBoard(ctx, boardID)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error %s, got %s", context.Canceled, err)
+	}
+}
+
+func TestClientUsesFreshCachedResponses(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+
+	client := fake.Client(WithCache(NewMemoryCache(), nil))
+
+	for i := 0; i < 2; i++ {
+		board, err := client.GetBoard(context.Background(), boardID)
+		if err != nil {
+			t.Fatalf("GetBoard returned error: %s", err)
+		}
+		if board.Name != "My Project" {
+			t.Errorf("Expected board name %s, got %s", "My Project", board.Name)
+		}
+	}
+	if fake.RequestCount() != 1 {
+		t.Errorf("Expected %d requests, got %d", 1, fake.RequestCount())
+	}
+}
+
+func TestClientRevalidatesStaleCachedResponses(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+
+	client := fake.Client(WithCache(NewMemoryCache(), &CacheTTLs{}))
+
+	for i := 0; i < 2; i++ {
+		board, err := client.GetBoard(context.Background(), boardID)
+		if err != nil {
+			t.Fatalf("GetBoard returned error: %s", err)
+		}
+		if board.Name != "My Project" {
+			t.Errorf("Expected board name %s, got %s", "My Project", board.Name)
+		}
+	}
+	if fake.RequestCount() != 2 {
+		t.Errorf("Expected %d requests, got %d", 2, fake.RequestCount())
+	}
+}
+
+func TestClientRejectsNotModifiedResponsesWithoutCachedResponse(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+	fake.InjectErrors(BoardPath(boardID), nil, http.StatusNotModified)
+
+	client := fake.Client(WithCache(NewMemoryCache(), nil))
+
+	_, err := client.GetBoard(context.Background(), boardID)
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected API error with status %d, got %v", http.StatusNotModified, err)
+	}
+}
+
+func TestClientBypassesCacheWhenRequested(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+
+	client := fake.Client(WithCache(NewMemoryCache(), nil))
+
+	if _, err := client.GetBoard(context.Background(), boardID); err != nil {
+		t.Fatalf("GetBoard returned error: %s", err)
+	}
+	if _, err := client.GetBoard(WithoutCache(context.Background()), boardID); err != nil {
+		t.Fatalf("GetBoard returned error: %s", err)
+	}
+	if fake.RequestCount() != 2 {
+		t.Errorf("Expected %d requests, got %d", 2, fake.RequestCount())
+	}
+}
 
-	client := Client{"some key", "some token"}
+func TestClientOnlyBatchesUncachedPaths(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
 
-	lists, err := client.ListsOnBoard("789")
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+	otherBoardID := fake.AddBoard(FakeBoard{Name: "Another Project"})
+
+	client := fake.Client(WithCache(NewMemoryCache(), nil))
+
+	if _, err := client.GetBoard(context.Background(), boardID); err != nil {
+		t.Fatalf("GetBoard returned error: %s", err)
+	}
+	boardsByID, _, err := client.GetBoards(context.Background(), []string{boardID, otherBoardID})
+	if err != nil {
+		t.Fatalf("GetBoards returned error: %s", err)
+	}
+	if len(boardsByID) != 2 {
+		t.Errorf("Expected %d boards, got %d", 2, len(boardsByID))
+	}
+	if _, _, err := client.GetBoards(context.Background(), []string{otherBoardID}); err != nil {
+		t.Fatalf("GetBoards returned error: %s", err)
+	}
+	expectedRequests := []string{"GET " + BoardPath(boardID), "GET /batch"}
+	if requests := fake.Requests(); fmt.Sprint(requests) != fmt.Sprint(expectedRequests) {
+		t.Errorf("Expected requests %v, got %v", expectedRequests, requests)
+	}
+}
+
+func TestClientExpiresCachedCardsWhenUpdatingACard(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+	listID := fake.AddList(FakeList{BoardID: boardID, Name: "Next Actions"})
+	cardID := fake.AddCard(FakeCard{ListID: listID, Name: "Todo Action"})
+
+	client := fake.Client(WithCache(NewMemoryCache(), nil))
+
+	if _, err := client.CardsOnList(context.Background(), listID); err != nil {
+		t.Fatalf("CardsOnList returned error: %s", err)
+	}
+	if err := client.ArchiveCard(context.Background(), cardID); err != nil {
+		t.Fatalf("ArchiveCard returned error: %s", err)
+	}
+	cards, err := client.CardsOnList(context.Background(), listID)
+	if err != nil {
+		t.Fatalf("CardsOnList returned error: %s", err)
+	}
+	if len(cards) != 0 {
+		t.Errorf("Expected the archived card to be gone, got %+v", cards)
+	}
+	if fake.RequestCount() != 3 {
+		t.Errorf("Expected %d requests, got %d", 3, fake.RequestCount())
+	}
+}
+
+func TestCacheTTLsDependOnResource(t *testing.T) {
+	ttls := CacheTTLs{Boards: time.Hour, Lists: time.Minute, Cards: time.Second}
+
+	testCases := map[string]time.Duration{
+		BoardPath("myBoardId"):        time.Hour,
+		ListsOnBoardPath("myBoardId"): time.Minute,
+		CardsOnListPath("myListId"):   time.Second,
+		OwnedCardsPath():              time.Second,
+		CardPath("myCardId"):          time.Second,
+	}
+	for relativePath, expectedTTL := range testCases {
+		if ttl := ttls.ttl(relativePath); ttl != expectedTTL {
+			t.Errorf("Expected TTL %s for %s, got %s", expectedTTL, relativePath, ttl)
+		}
+	}
+}
+
+func TestNewClientUsesBaseURLAndHTTPClient(t *testing.T) {
+	var requestedURL *url.URL
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requestedURL = req.URL
+		fmt.Fprint(w, `{"id": "myBoardId", "name": "My Project"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("some key", "some token", WithBaseURL(server.URL+"/1"), WithHTTPClient(server.Client()))
+
+	board, err := client.GetBoard(context.Background(), "myBoardId")
+	if err != nil {
+		t.Fatalf("GetBoard returned error: %s", err)
+	}
+	if board.Name != "My Project" {
+		t.Errorf("Expected board name %s, got %s", "My Project", board.Name)
+	}
+	if requestedURL.Path != "/1/boards/myBoardId" {
+		t.Errorf("Expected request to %s, got %s", "/1/boards/myBoardId", requestedURL.Path)
+	}
+	if requestedURL.Query().Get("key") != "some key" || requestedURL.Query().Get("token") != "some token" {
+		t.Errorf("Expected key and token in query, got %s", requestedURL.RawQuery)
+	}
+}
+
+func TestNewClientDefaults(t *testing.T) {
+	client := NewClient("some key", "some token")
+
+	if client.baseURL() != APIBaseURL {
+		t.Errorf("Expected base URL %s, got %s", APIBaseURL, client.baseURL())
+	}
+	if client.HTTPClient == nil || client.HTTPClient.Timeout != DefaultHTTPTimeout {
+		t.Errorf("Expected HTTP client with timeout %s, got %+v", DefaultHTTPTimeout, client.HTTPClient)
+	}
+}
+
+func TestFakeServerServesState(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project", BackgroundImageURLs: []string{"https://example.com/a.jpg"}})
+	todoListID := fake.AddList(FakeList{BoardID: boardID, Name: "Todo"})
+	fake.AddList(FakeList{BoardID: boardID, Name: "Done"})
+	dueBy, _ := time.Parse(time.RFC3339, "2020-01-01T10:30:00Z")
+	cardID := fake.AddOwnedCard(FakeCard{ListID: todoListID, Name: "My Action", Due: &dueBy})
+	fake.AddCard(FakeCard{ListID: todoListID, Name: "Someone Else's Action"})
+	fake.AddCard(FakeCard{ListID: todoListID, Name: "Archived Action", Closed: true})
+
+	client := fake.Client()
+
+	ownedCards, err := client.OwnedCards(context.Background())
 	if err != nil {
-		t.Errorf("ListsOnBoard returned error: %s", err)
+		t.Fatalf("OwnedCards returned error: %s", err)
 	}
-	if len(lists) != 2 {
-		t.Fatalf("ListsOnBoard returned %d lists, expected %d", len(lists), 2)
+	if len(ownedCards) != 1 || ownedCards[0].ID != cardID || !ownedCards[0].DueBy.Equal(dueBy) {
+		t.Errorf("Expected only card %s, got %+v", cardID, ownedCards)
 	}
-	expectedList1 := List{"inboxListId", "Inbox"}
-	expectedList2 := List{"todoListId", "Todo"}
-	if lists[0] != expectedList1 {
-		t.Errorf(fmt.Sprintf("ListsOnBoard returned incorrect list, expected %+v got %+v", expectedList1, lists[0]))
+
+	todoCards, err := client.CardsOnList(context.Background(), todoListID)
+	if err != nil {
+		t.Fatalf("CardsOnList returned error: %s", err)
 	}
-	if lists[1] != expectedList2 {
-		t.Errorf(fmt.Sprintf("ListsOnBoard returned incorrect list, expected %+v got %+v", expectedList2, lists[1]))
+	if len(todoCards) != 2 || todoCards[0].BoardID != boardID {
+		t.Errorf("Expected 2 open cards on board %s, got %+v", boardID, todoCards)
+	}
+
+	lists, err := client.ListsOnBoard(context.Background(), boardID)
+	if err != nil {
+		t.Fatalf("ListsOnBoard returned error: %s", err)
+	}
+	if len(lists) != 2 || lists[0].Name != "Todo" || lists[1].Name != "Done" {
+		t.Errorf("Expected lists Todo and Done, got %+v", lists)
+	}
+
+	boardsByID, errorsByID, err := client.GetBoards(context.Background(), []string{boardID, "missingBoardId"})
+	if err != nil {
+		t.Fatalf("GetBoards returned error: %s", err)
+	}
+	if boardsByID[boardID].Name != "My Project" || len(boardsByID[boardID].Preferences.BackgroundImages) != 1 {
+		t.Errorf("GetBoards returned incorrect board %+v", boardsByID[boardID])
+	}
+	if !IsNotFound(errorsByID["missingBoardId"]) {
+		t.Errorf("Expected not found error for missing board, got %s", errorsByID["missingBoardId"])
 	}
 }
 
-func TestClientGetBoard(t *testing.T) {
-	mockServer := CreateMockServer("some key", "some token")
-	defer TeardownMockServer()
+func TestClientFetchesArchivedCardsFromFakeServerIfAsked(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+	todoListID := fake.AddList(FakeList{BoardID: boardID, Name: "Todo"})
+	fake.AddCard(FakeCard{ListID: todoListID, Name: "Open Action"})
+	archivedCardID := fake.AddOwnedCard(FakeCard{ListID: todoListID, Name: "Archived Action", Closed: true})
+
+	client := fake.Client()
+	ctx := WithArchivedCards(context.Background())
 
-	mockServer.AddFileResponse(BoardPath("myBoardId"), "./testdata/board_response.json")
+	ownedCards, err := client.OwnedCards(ctx)
+	if err != nil {
+		t.Fatalf("OwnedCards returned error: %s", err)
+	}
+	if len(ownedCards) != 1 || ownedCards[0].ID != archivedCardID || !ownedCards[0].Closed {
+		t.Errorf("Expected only archived card %s, got %+v", archivedCardID, ownedCards)
+	}
 
-	client := Client{"some key", "some token"}
+	todoCards, err := client.CardsOnList(ctx, todoListID)
+	if err != nil {
+		t.Fatalf("CardsOnList returned error: %s", err)
+	}
+	if len(todoCards) != 2 {
+		t.Errorf("Expected open and archived cards, got %+v", todoCards)
+	}
 
-	board, err := client.GetBoard("myBoardId")
+	cardsByListID, _, err := client.CardsWithChecklistsOnLists(ctx, []string{todoListID})
 	if err != nil {
-		t.Errorf("GetBoard returned error: %s", err)
+		t.Fatalf("CardsWithChecklistsOnLists returned error: %s", err)
+	}
+	if len(cardsByListID[todoListID]) != 2 {
+		t.Errorf("Expected open and archived cards in batch, got %+v", cardsByListID)
 	}
 
-	backgroundURL1, _ := url.Parse("https://trello-backgrounds.s3.amazonaws.com/SharedBackground/75x100.jpg")
-	backgroundURL2, _ := url.Parse("https://trello-backgrounds.s3.amazonaws.com/SharedBackground/144x192.jpg")
-	backgroundImages := []BackgroundImage{
-		{*backgroundURL1},
-		{*backgroundURL2},
+	openCards, err := client.CardsOnList(context.Background(), todoListID)
+	if err != nil {
+		t.Fatalf("CardsOnList returned error: %s", err)
 	}
-	expectedBoard := Board{"myBoardId", "My Project", Preferences{backgroundImages}}
-	if expectedBoard.ID != board.ID || expectedBoard.Name != board.Name {
-		t.Errorf(fmt.Sprintf("GetBoard returned incorrect board, expected %+v got %+v", expectedBoard, board))
+	if len(openCards) != 1 || openCards[0].Closed {
+		t.Errorf("Expected only the open card without the archived context, got %+v", openCards)
 	}
-	if len(expectedBoard.Preferences.BackgroundImages) != len(board.Preferences.BackgroundImages) {
-		t.Fatalf(fmt.Sprintf("GetBoard returned incorrect board, expected %+v got %+v", expectedBoard, board))
+}
+
+func TestFakeServerAppliesWrites(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+	todoListID := fake.AddList(FakeList{BoardID: boardID, Name: "Todo"})
+	doneListID := fake.AddList(FakeList{BoardID: boardID, Name: "Done"})
+	cardID := fake.AddCard(FakeCard{ListID: todoListID, Name: "My Action"})
+
+	client := fake.Client()
+
+	if err := client.MoveCardToList(context.Background(), cardID, doneListID); err != nil {
+		t.Fatalf("MoveCardToList returned error: %s", err)
 	}
-	for i, expectedBackgroundImage := range expectedBoard.Preferences.BackgroundImages {
-		if expectedBackgroundImage.URL.String() != board.Preferences.BackgroundImages[i].URL.String() {
-			t.Errorf(fmt.Sprintf("GetBoard returned incorrect board, expected %+v got %+v", expectedBoard, board))
+	if err := client.MarkCardDueComplete(context.Background(), cardID); err != nil {
+		t.Fatalf("MarkCardDueComplete returned error: %s", err)
+	}
+
+	card, _ := fake.Card(cardID)
+	if card.ListID != doneListID || !card.DueComplete {
+		t.Errorf("Expected card on list %s and due complete, got %+v", doneListID, card)
+	}
+
+	if err := client.MoveCardToList(context.Background(), cardID, "missingListId"); err == nil {
+		t.Error("Expected error moving card to missing list")
+	}
+}
+
+func TestFakeServerRejectsInvalidKey(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	client := NewClient("wrong key", "some token", WithBaseURL(fake.URL()))
+
+	_, err := client.OwnedCards(context.Background())
+	if !IsUnauthorized(err) {
+		t.Errorf("Expected unauthorized error, got %s", err)
+	}
+}
+
+func TestFakeServerInjectsErrors(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+	fake.InjectErrors(BoardPath(boardID), nil, http.StatusTooManyRequests, http.StatusBadGateway)
+
+	client := fake.Client(WithRetryPolicy(testRetryPolicy()))
+
+	board, err := client.GetBoard(context.Background(), boardID)
+	if err != nil {
+		t.Fatalf("GetBoard returned error: %s", err)
+	}
+	if board.Name != "My Project" {
+		t.Errorf("Expected board name %s, got %s", "My Project", board.Name)
+	}
+	if fake.RequestCount() != 3 {
+		t.Errorf("Expected %d requests, got %d", 3, fake.RequestCount())
+	}
+}
+
+func TestRequestLimiterIsNotHeldWhileWaitingToRetry(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	retryingBoardID := fake.AddBoard(FakeBoard{Name: "Retrying"})
+	otherBoardID := fake.AddBoard(FakeBoard{Name: "Other"})
+	fake.InjectErrors(BoardPath(retryingBoardID), nil, http.StatusServiceUnavailable)
+
+	client := fake.Client(WithRetryPolicy(&RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Second}))
+	ctx := WithRequestLimiter(context.Background(), NewRequestLimiter(1))
+
+	retryDone := make(chan error, 1)
+	go func() {
+		_, err := client.GetBoard(ctx, retryingBoardID)
+		retryDone <- err
+	}()
+	for fake.RequestCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := client.GetBoard(ctx, otherBoardID); err != nil {
+		t.Fatalf("GetBoard returned error: %s", err)
+	}
+	select {
+	case <-retryDone:
+		t.Errorf("Expected another request to be made while the first was waiting to retry")
+	default:
+	}
+	if err := <-retryDone; err != nil {
+		t.Errorf("Expected the retried request to succeed, got %s", err)
+	}
+}
+
+func TestFakeServerRejectsInjectingNoErrors(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected InjectErrors to panic without any status codes")
 		}
+	}()
+	fake.InjectErrors("/boards", nil)
+}
+
+func TestFakeServerInjectsLatency(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	fake.SetLatency(time.Second)
+
+	client := fake.Client()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.OwnedCards(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error %s, got %s", context.DeadlineExceeded, err)
 	}
 }
 
-func TestClientHandlesHTTPErrors(t *testing.T) {
-	CreateMockServer("some key", "some token")
-	defer TeardownMockServer()
+// newCassetteSandbox creates the board that the cassettes in testdata/cassettes are recorded from by
+// `make record-sandbox-cassettes`, laid out in the same way as a real account using the app: a GTD board with Next
+// Actions and Projects lists, and a project board linked from the Projects list
+func newCassetteSandbox() *FakeServer {
+	fake := NewFakeServer("sandbox key", "sandbox token")
+	lastActivity, _ := time.Parse(time.RFC3339, "2020-02-27T21:46:45.202Z")
+	dueBy, _ := time.Parse(time.RFC3339, "2020-01-15T10:29:59.000Z")
 
-	client := Client{"some key", "some token"}
+	gtdBoardID := fake.AddBoard(FakeBoard{Name: "GTD"})
+	officeLabelID := fake.AddLabel(FakeLabel{BoardID: gtdBoardID, Name: "@office", Color: "green"})
+	nextActionsListID := fake.AddList(FakeList{BoardID: gtdBoardID, Name: "Next Actions"})
+	projectsListID := fake.AddList(FakeList{BoardID: gtdBoardID, Name: "Projects"})
+	fake.AddOwnedCard(FakeCard{
+		ListID:           nextActionsListID,
+		Name:             "My First Action",
+		Due:              &dueBy,
+		LabelIDs:         []string{officeLabelID},
+		DateLastActivity: lastActivity,
+	})
+	fake.AddCard(FakeCard{ListID: nextActionsListID, Name: "Todo Action", DateLastActivity: lastActivity})
 
-	_, err := client.GetBoard("myBoardId")
-	if err == nil {
-		t.Error("Client did not return 404 error", err)
+	projectBoardID := fake.AddBoard(FakeBoard{
+		Name: "My Project",
+		BackgroundImageURLs: []string{
+			"https://trello-backgrounds.s3.amazonaws.com/SharedBackground/75x100.jpg",
+			"https://trello-backgrounds.s3.amazonaws.com/SharedBackground/144x192.jpg",
+		},
+	})
+	projectBoard, _ := fake.Board(projectBoardID)
+	fake.AddCard(FakeCard{
+		ListID:           projectsListID,
+		Name:             "My Project",
+		Attachments:      []FakeAttachment{{Name: "My Project", URL: BoardBaseURL + projectBoard.ShortLink}},
+		DateLastActivity: lastActivity,
+	})
+	todoListID := fake.AddList(FakeList{BoardID: projectBoardID, Name: "Todo"})
+	projectCardID := fake.AddOwnedCard(FakeCard{
+		ListID:           todoListID,
+		Name:             "Project Action",
+		DateLastActivity: lastActivity,
+	})
+	fake.AddChecklist(FakeChecklist{
+		CardID: projectCardID,
+		Name:   "Checklist",
+		Items:  []FakeCheckItem{{Name: "Done", Complete: true}, {Name: "Not done"}},
+	})
+
+	return fake
+}
+
+// TestCassetteClient replays the responses in testdata/cassettes/client.json, to check that the client can decode
+// them. The cassette is recorded rather than written by hand, and can be re-recorded from a real account, so the test
+// only checks the shape of the data and how the responses relate to each other rather than particular values.
+func TestCassetteClient(t *testing.T) {
+	client, finish, err := NewCassetteClient("./testdata/cassettes/client.json", newCassetteSandbox)
+	if err != nil {
+		t.Fatalf("NewCassetteClient returned error: %s", err)
 	}
+	ctx := context.Background()
 
-	expectedError := fmt.Errorf("request to %s returned status code %d", BoardPath("myBoardId"), 404)
-	if err.Error() != expectedError.Error() {
-		t.Errorf("Expected error %s, got %s", expectedError, err)
+	member, err := client.CurrentMember(ctx)
+	if err != nil || member.ID == "" || member.Username == "" {
+		t.Errorf("Expected member with ID and username, got %+v and error %s", member, err)
+	}
+
+	ownedCards, err := client.OwnedCards(ctx)
+	if err != nil || len(ownedCards) == 0 {
+		t.Fatalf("Expected owned cards, got %+v and error %s", ownedCards, err)
+	}
+	assertCardsHaveExpectedShape(t, ownedCards)
+	card, err := client.GetCard(ctx, ownedCards[0].ID)
+	if err != nil || !cardsAreEqual(card, &ownedCards[0]) {
+		t.Errorf("Expected card %+v, got %+v and error %s", ownedCards[0], card, err)
+	}
+
+	board, err := client.GetBoard(ctx, ownedCards[0].BoardID)
+	if err != nil || board.ID != ownedCards[0].BoardID || board.Name == "" {
+		t.Errorf("Expected board %s with a name, got %+v and error %s", ownedCards[0].BoardID, board, err)
+	}
+	lists, err := client.ListsOnBoard(ctx, ownedCards[0].BoardID)
+	if err != nil || !containsList(lists, ownedCards[0].ListID) {
+		t.Errorf("Expected list %s on board, got %+v and error %s", ownedCards[0].ListID, lists, err)
+	}
+
+	cardsOnList, err := client.CardsOnList(ctx, ownedCards[0].ListID)
+	if err != nil || !containsCard(cardsOnList, ownedCards[0].ID) {
+		t.Errorf("Expected card %s on list, got %+v and error %s", ownedCards[0].ID, cardsOnList, err)
+	}
+	assertCardsAreOnList(t, cardsOnList, ownedCards[0].BoardID, ownedCards[0].ListID)
+	assertCardsHaveExpectedShape(t, cardsOnList)
+
+	cardsWithAttachments, err := client.CardsWithAttachmentsOnList(ctx, ownedCards[0].ListID)
+	if err != nil || len(cardsWithAttachments) != len(cardsOnList) {
+		t.Errorf("Expected %d cards with attachments, got %+v and error %s", len(cardsOnList), cardsWithAttachments, err)
+	}
+
+	boards, err := client.OwnedBoards(ctx)
+	if err != nil || !containsBoard(boards, ownedCards[0].BoardID) {
+		t.Errorf("Expected board %s in owned boards, got %+v and error %s", ownedCards[0].BoardID, boards, err)
+	}
+
+	if err := finish(); err != nil {
+		t.Errorf("Could not save cassette: %s", err)
+	}
+}
+
+// TestCassetteBatches replays the batched responses in testdata/cassettes/batch.json, which are recorded in the same
+// way as those used by TestCassetteClient
+func TestCassetteBatches(t *testing.T) {
+	client, finish, err := NewCassetteClient("./testdata/cassettes/batch.json", newCassetteSandbox)
+	if err != nil {
+		t.Fatalf("NewCassetteClient returned error: %s", err)
+	}
+	ctx := context.Background()
+
+	boards, err := client.OwnedBoards(ctx)
+	if err != nil || len(boards) == 0 {
+		t.Fatalf("Expected owned boards, got %+v and error %s", boards, err)
+	}
+	boardIDs := make([]string, len(boards))
+	for i, board := range boards {
+		boardIDs[i] = board.ID
+	}
+
+	boardsByID, errorsByBoardID, err := client.GetBoards(ctx, boardIDs)
+	if err != nil || len(errorsByBoardID) != 0 || len(boardsByID) != len(boardIDs) {
+		t.Errorf("Expected %d boards, got %+v and errors %+v %s", len(boardIDs), boardsByID, errorsByBoardID, err)
+	}
+	for boardID, board := range boardsByID {
+		if board.ID != boardID || board.Name == "" {
+			t.Errorf("Expected board %s with a name, got %+v", boardID, board)
+		}
+	}
+
+	listsByBoardID, errorsByBoardID, err := client.ListsOnBoards(ctx, boardIDs)
+	if err != nil || len(errorsByBoardID) != 0 {
+		t.Fatalf("ListsOnBoards returned errors %+v %s", errorsByBoardID, err)
+	}
+	listIDs := make([]string, 0)
+	for _, boardID := range boardIDs {
+		for _, list := range listsByBoardID[boardID] {
+			listIDs = append(listIDs, list.ID)
+		}
+	}
+	if len(listIDs) == 0 {
+		t.Fatalf("Expected lists on owned boards, got %+v", listsByBoardID)
+	}
+
+	cardsByListID, errorsByListID, err := client.CardsWithChecklistsOnLists(ctx, listIDs)
+	if err != nil || len(errorsByListID) != 0 {
+		t.Fatalf("CardsWithChecklistsOnLists returned errors %+v %s", errorsByListID, err)
+	}
+	for listID, cards := range cardsByListID {
+		assertCardsHaveExpectedShape(t, cards)
+		assertCardsHaveExpectedChecklists(t, cards, listID)
+	}
+
+	if err := finish(); err != nil {
+		t.Errorf("Could not save cassette: %s", err)
+	}
+}
+
+func assertCardsHaveExpectedShape(t *testing.T, cards []Card) {
+	for _, card := range cards {
+		if card.ID == "" || card.Name == "" || card.BoardID == "" || card.ListID == "" {
+			t.Errorf("Expected card to have an ID, name, board and list, got %+v", card)
+		}
+		if card.URL.Host != "trello.com" || card.LastActivity.IsZero() {
+			t.Errorf("Expected card to have a Trello URL and last activity, got %+v", card)
+		}
+		for _, label := range card.Labels {
+			if label.ID == "" {
+				t.Errorf("Expected labels on card %s to have IDs, got %+v", card.ID, label)
+			}
+		}
+	}
+}
+
+func assertCardsHaveExpectedChecklists(t *testing.T, cards []Card, listID string) {
+	for _, card := range cards {
+		if card.ListID != listID {
+			t.Errorf("Expected card %s to be on list %s, got %s", card.ID, listID, card.ListID)
+		}
+		for _, checklist := range card.Checklists {
+			for _, checkItem := range checklist.CheckItems {
+				if checkItem.Name == "" {
+					t.Errorf("Expected check items on card %s to have names, got %+v", card.ID, checkItem)
+				}
+			}
+		}
+	}
+}
+
+func assertCardsAreOnList(t *testing.T, cards []Card, boardID, listID string) {
+	for _, card := range cards {
+		if card.BoardID != boardID || card.ListID != listID {
+			t.Errorf("Expected card %s to be on list %s of board %s, got %+v", card.ID, listID, boardID, card)
+		}
+	}
+}
+
+func containsCard(cards []Card, cardID string) bool {
+	for _, card := range cards {
+		if card.ID == cardID {
+			return true
+		}
+	}
+	return false
+}
+
+func containsList(lists []List, listID string) bool {
+	for _, list := range lists {
+		if list.ID == listID {
+			return true
+		}
+	}
+	return false
+}
+
+func containsBoard(boards []Board, boardID string) bool {
+	for _, board := range boards {
+		if board.ID == boardID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRecorderScrubsKeyAndTokenAndCanBeReplayed(t *testing.T) {
+	fake := NewFakeServer("secret key", "secret token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "secret key and secret token"})
+
+	recorder := NewRecorder(http.DefaultTransport, "secret key", "secret token")
+	client := NewClient("secret key", "secret token", WithBaseURL(fake.URL()), WithHTTPClient(&http.Client{
+		Transport: recorder,
+	}))
+	if _, err := client.GetBoard(context.Background(), boardID); err != nil {
+		t.Fatalf("GetBoard returned error: %s", err)
+	}
+
+	recorded, _ := json.Marshal(recorder.Cassette())
+	if strings.Contains(string(recorded), "secret key") || strings.Contains(string(recorded), "secret token") {
+		t.Errorf("Expected key and token to be scrubbed from cassette, got %s", recorded)
+	}
+
+	noRetryPolicy := NoRetryPolicy()
+	replayClient := NewClient("other key", "other token", WithBaseURL(fake.URL()), WithRetryPolicy(&noRetryPolicy),
+		WithHTTPClient(&http.Client{Transport: NewReplayer(recorder.Cassette())}))
+	board, err := replayClient.GetBoard(context.Background(), boardID)
+	if err != nil {
+		t.Fatalf("GetBoard returned error: %s", err)
+	}
+	if board.Name != "SCRUBBED and SCRUBBED" {
+		t.Errorf("Expected board name %s, got %s", "SCRUBBED and SCRUBBED", board.Name)
+	}
+	if fake.RequestCount() != 1 {
+		t.Errorf("Expected %d requests, got %d", 1, fake.RequestCount())
+	}
+
+	if _, err := replayClient.GetBoard(context.Background(), "unrecordedBoardId"); err == nil {
+		t.Error("Expected error for unrecorded request")
+	}
+}
+
+func TestCardsWithAttachmentsOnList(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "GTD"})
+	listID := fake.AddList(FakeList{BoardID: boardID, Name: "Projects"})
+	fake.AddCard(FakeCard{
+		ListID:      listID,
+		Name:        "My Project",
+		Description: "Notes",
+		Attachments: []FakeAttachment{{Name: "Board", URL: "https://trello.com/b/abcd1234"}},
+	})
+
+	client := fake.Client()
+	cards, err := client.CardsWithAttachmentsOnList(context.Background(), listID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 1 || cards[0].Description != "Notes" || len(cards[0].Attachments) != 1 {
+		t.Fatalf("Expected card with description and attachment, got %+v", cards)
+	}
+	attachment := cards[0].Attachments[0]
+	if attachment.Name != "Board" || attachment.URL.String() != "https://trello.com/b/abcd1234" {
+		t.Errorf("Expected board attachment, got %+v", attachment)
+	}
+
+	cards, err = client.CardsOnList(context.Background(), listID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 1 || len(cards[0].Attachments) != 0 {
+		t.Errorf("Expected card without attachments, got %+v", cards)
+	}
+}
+
+func TestCardsWithChecklistsOnLists(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	boardID := fake.AddBoard(FakeBoard{Name: "My Project"})
+	listID := fake.AddList(FakeList{BoardID: boardID, Name: "Todo"})
+	labelID := fake.AddLabel(FakeLabel{BoardID: boardID, Name: "Waiting", Color: "yellow"})
+	cardID := fake.AddCard(FakeCard{ListID: listID, Name: "Blocked", LabelIDs: []string{labelID}})
+	fake.AddChecklist(FakeChecklist{
+		CardID: cardID,
+		Name:   "Dependencies",
+		Items:  []FakeCheckItem{{Name: "Done", Complete: true}, {Name: "Not done"}},
+	})
+
+	cardsByListID, errorsByListID, err := fake.Client().CardsWithChecklistsOnLists(context.Background(), []string{listID})
+	if err != nil || len(errorsByListID) > 0 {
+		t.Fatalf("Unexpected errors: %v %v", err, errorsByListID)
+	}
+
+	cards := cardsByListID[listID]
+	if len(cards) != 1 || len(cards[0].Labels) != 1 || len(cards[0].Checklists) != 1 {
+		t.Fatalf("Expected card with label and checklist, got %+v", cards)
+	}
+	if label := cards[0].Labels[0]; label.Name != "Waiting" || label.Color != "yellow" {
+		t.Errorf("Expected Waiting label, got %+v", label)
+	}
+	checkItems := cards[0].Checklists[0].CheckItems
+	if len(checkItems) != 2 || !checkItems[0].IsComplete() || checkItems[1].IsComplete() {
+		t.Errorf("Expected one complete and one incomplete item, got %+v", checkItems)
 	}
 }
 
-func assertCardsMatchExpected(t *testing.T, cards, expectedCards []Card) {
-	if len(expectedCards) != len(cards) {
-		t.Fatalf("Unexpected number of card returned, expected %d and got %d", len(expectedCards), len(cards))
+func TestFetchDirectory(t *testing.T) {
+	fake := NewFakeServer("some key", "some token")
+	defer fake.Close()
+
+	workBoardID := fake.AddBoard(FakeBoard{Name: "work"})
+	gtdBoardID := fake.AddBoard(FakeBoard{Name: "GTD"})
+	fake.AddBoard(FakeBoard{Name: "Old", Closed: true})
+	nextActionsListID := fake.AddList(FakeList{BoardID: gtdBoardID, Name: "Next Actions"})
+
+	directory, err := fake.Client().FetchDirectory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(directory.Boards) != 2 || directory.Boards[0].ID != gtdBoardID || directory.Boards[1].ID != workBoardID {
+		t.Fatalf("Expected open boards sorted by name, got %+v", directory.Boards)
+	}
+	lists := directory.ListsByBoardID[gtdBoardID]
+	if len(lists) != 1 || lists[0].ID != nextActionsListID {
+		t.Errorf("Expected Next Actions list on GTD board, got %+v", lists)
+	}
+}
+
+func TestDirectoryFindList(t *testing.T) {
+	directory := &Directory{
+		Boards: []Board{
+			{ID: "gtdBoard", Name: "GTD"},
+			{ID: "clientBoard", Name: "Client A/B"},
+			{ID: "duplicateBoard1", Name: "Duplicate"},
+			{ID: "duplicateBoard2", Name: "Duplicate"},
+		},
+		ListsByBoardID: map[string][]List{
+			"gtdBoard":        {{ID: "nextActionsList", Name: "Next Actions"}, {ID: "projectsList", Name: "Projects"}},
+			"clientBoard":     {{ID: "clientTodoList", Name: "Todo"}},
+			"duplicateBoard1": {{ID: "duplicateList1", Name: "Todo"}},
+			"duplicateBoard2": {{ID: "duplicateList2", Name: "Todo"}},
+		},
+	}
+
+	foundTests := []struct {
+		reference      string
+		expectedListID string
+	}{
+		{"GTD/Next Actions", "nextActionsList"},
+		{"gtd / projects", "projectsList"},
+		{"Client A/B/Todo", "clientTodoList"},
+	}
+	for _, test := range foundTests {
+		list, err := directory.FindList(test.reference)
+		if err != nil {
+			t.Errorf("Expected %q to be found, got error %s", test.reference, err)
+		} else if list.ID != test.expectedListID {
+			t.Errorf("Expected %q to find list %s, got %s", test.reference, test.expectedListID, list.ID)
+		}
+	}
+
+	errorTests := []struct {
+		reference     string
+		expectedError string
+	}{
+		{"Next Actions", `list reference "Next Actions" must be of the form "board name/list name"`},
+		{"Missing/Todo", `list reference "Missing/Todo" does not match any board`},
+		{"GTD/Missing", `list reference "GTD/Missing" does not match any list on the board`},
+		{"Duplicate/Todo", `list reference "Duplicate/Todo" is ambiguous, it matches 2 lists`},
 	}
-	for i := range cards {
-		if !cardsAreEqual(&expectedCards[i], &cards[i]) {
-			t.Errorf("Expected card %d to be %+v but got %+v", i, expectedCards[i], cards[i])
+	for _, test := range errorTests {
+		_, err := directory.FindList(test.reference)
+		if err == nil || err.Error() != test.expectedError {
+			t.Errorf("Expected error %q for %q, got %v", test.expectedError, test.reference, err)
 		}
 	}
 }
@@ -157,5 +992,6 @@ func cardsAreEqual(card, other *Card) bool {
 		card.Name == other.Name &&
 		((card.DueBy == nil && other.DueBy == nil) || card.DueBy.Equal(*other.DueBy)) &&
 		card.URL.String() == other.URL.String() &&
-		card.BoardID == other.BoardID)
+		card.BoardID == other.BoardID &&
+		card.ListID == other.ListID)
 }